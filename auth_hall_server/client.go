@@ -7,5 +7,5 @@ var (
 )
 
 func initClient() {
-	clients = types.NewGameServerRpc(gameServerRpcPort)
+	clients = types.NewGameServerRpc(gameServerRpcPort, privTls)
 }
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"crypto/tls"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/astaxie/beego/config"
 	"github.com/gogames/go_tetris/utils"
+	"github.com/gogames/go_tetris/utils/ratelimit"
 )
 
 var (
@@ -16,10 +19,16 @@ var (
 	logPath                                             string
 	privRpcPort, pubRpcPort, tournamentPort             string
 	emailId, emailUser, emailPass, emailHost, emailFrom string
-	cookieEncryptKey, tokenEncryptKey, scryptSalt       string
+	cookieEncryptKey, tokenKeys, scryptSalt             string
 	emailSMTPPort                                       int
 	cookieDomain, crossDomainFile                       string
-	privKey, tournamentKey                              []byte
+	tournamentKey                                       []byte
+	privKeys                                            map[string][]byte // game server id -> key
+	privIps                                             map[string]string // game server id -> ip, the allowlist
+	tlsCert, tlsKey                                     string            // of the private rpc server, optional
+	privTls                                             *tls.Config       // to the game servers, nil for plain http
+	rateLimits                                          map[string]ratelimit.Policy
+	rateLimitAllowlist                                  []string // ips or uid:N not limited
 )
 
 func initConf() {
@@ -44,7 +53,13 @@ func initConf() {
 	privRpcPort = conf.String("privServerRpcPort")
 	pubRpcPort = conf.String("publicServerRpcPort")
 	tournamentPort = conf.String("tournamentPort")
-	privKeyString := conf.String("privKey")
+	privKeysString := conf.String("gameServerKeys")
+	privIpsString := conf.String("gameServerIps")
+	tlsCert = conf.String("tlsCert")
+	tlsKey = conf.String("tlsKey")
+	tlsCa := conf.String("tlsCa")
+	rateLimitsString := conf.String("rateLimits")
+	rateLimitAllowlistString := conf.String("rateLimitAllowlist")
 	tournamentKeyString := conf.String("tournamentKey")
 	emailId = conf.String("emailIdentity")
 	emailUser = conf.String("emailUsername")
@@ -52,7 +67,7 @@ func initConf() {
 	emailHost = conf.String("emailHost")
 	emailFrom = conf.String("emailFrom")
 	cookieEncryptKey = conf.String("cookieEncryptKey")
-	tokenEncryptKey = conf.String("tokenEncryptKey")
+	tokenKeys = conf.String("tokenKeys")
 	scryptSalt = conf.String("scryptSalt")
 	cookieDomain = conf.String("domain")
 	crossDomainFile = conf.String("crossDomainFile")
@@ -64,20 +79,57 @@ func initConf() {
 	utils.CheckEmptyConf(btcUser, btcPass, btcServer,
 		gameServerRpcPort, gamePubServerPort, tournamentPort,
 		dbUser, dbPass, dbProtocol, dbSockAddress, dbName,
-		logPath, privRpcPort, pubRpcPort, privKeyString, tournamentKeyString,
+		logPath, privRpcPort, pubRpcPort, privKeysString, privIpsString, tournamentKeyString,
 		emailId, emailFrom, emailHost, emailPass, emailUser, emailSMTPPort,
-		cookieEncryptKey, tokenEncryptKey, scryptSalt, crossDomainFile)
+		cookieEncryptKey, tokenKeys, scryptSalt, crossDomainFile)
 
 	// done configuration checking
 	// initialize
 	dsn = fmt.Sprintf("%s:%s@%s(%s)/%s", dbUser, dbPass, dbProtocol, dbSockAddress, dbName)
 	utils.SetCookieKey([]byte(cookieEncryptKey))
-	utils.SetTokenKey([]byte(tokenEncryptKey))
+	if err := utils.SetTokenKeys(tokenKeys); err != nil {
+		panic("can not parse token keys: " + err.Error())
+	}
 	utils.SetScryptSalt([]byte(scryptSalt))
 	utils.SetEmailConf(emailId, emailUser, emailPass, emailHost, emailFrom, emailSMTPPort)
 	if cookieDomain != "" {
 		utils.SetDomain(cookieDomain)
 	}
-	privKey = []byte(privKeyString)
+	if privKeys, err = utils.ParsePrivKeys(privKeysString); err != nil {
+		panic("can not parse game server keys: " + err.Error())
+	}
+	if privIps, err = parsePrivIps(privIpsString); err != nil {
+		panic("can not parse game server ips: " + err.Error())
+	}
+	if (tlsCert == "") != (tlsKey == "") {
+		panic("tlsCert and tlsKey should be set together")
+	}
+	if tlsCa != "" {
+		if privTls, err = utils.PrivTlsConfig(tlsCa); err != nil {
+			panic("can not read tls ca: " + err.Error())
+		}
+	}
+	if rateLimits, err = ratelimit.ParsePolicies(rateLimitsString); err != nil {
+		panic("can not parse rate limits: " + err.Error())
+	}
+	rateLimitAllowlist = strings.Split(rateLimitAllowlistString, ",")
 	tournamentKey = []byte(tournamentKeyString)
 }
+
+// "id:ip,id:ip", every game server with a key should have an ip
+func parsePrivIps(s string) (map[string]string, error) {
+	ips := make(map[string]string)
+	for _, kv := range strings.Split(s, ",") {
+		vals := strings.SplitN(strings.TrimSpace(kv), ":", 2)
+		if len(vals) != 2 || privKeys[vals[0]] == nil || vals[1] == "" {
+			return nil, fmt.Errorf("%q should be id:ip of a game server with a key", kv)
+		}
+		ips[vals[0]] = vals[1]
+	}
+	for id := range privKeys {
+		if _, ok := ips[id]; !ok {
+			return nil, fmt.Errorf("the ip of game server %s is not set", id)
+		}
+	}
+	return ips, nil
+}
@@ -30,6 +30,13 @@ const (
 		session BLOB,
 		PRIMARY KEY (sessionId)
 	) ENGINE=innoDB;`
+	sqlCreateForfeit = `CREATE TABLE forfeits (
+		tid INT,
+		winner INT,
+		loser INT,
+		reason VARCHAR(32), -- disconnect, afk
+		created INT
+	) ENGINE=innoDB;`
 )
 
 var errDepositTxidExisted = fmt.Errorf("deposit txid exist")
@@ -63,6 +70,9 @@ func createTable() {
 	if _, err := db.Exec(sqlCreateSession); err != nil {
 		log.Debug("can not create session table: %v", err)
 	}
+	if _, err := db.Exec(sqlCreateForfeit); err != nil {
+		log.Debug("can not create forfeit table: %v", err)
+	}
 }
 
 // init set bitcoin freezed to 0, add it to balance
@@ -185,6 +195,14 @@ func insertOrUpdateUser(us ...*types.User) {
 	}
 }
 
+// record the forfeit of a game
+func insertForfeit(tid, winner, loser int, reason string) {
+	if _, err := db.Exec("INSERT INTO forfeits(tid, winner, loser, reason, created) VALUES(?, ?, ?, ?, ?)",
+		tid, winner, loser, reason, time.Now().Unix()); err != nil {
+		log.Error("can not insert forfeit of table %d: %v", tid, err)
+	}
+}
+
 // store session into db before the program exit
 func storeSession(sesses map[string]map[string]interface{}) {
 	tx, err := db.Begin()
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gogames/go_tetris/types"
+	"github.com/gogames/go_tetris/utils"
+	"github.com/gogames/go_tetris/utils/queue"
+)
+
+// the events of the hall, besides types.TableCreated, types.TableUpdated and types.TableRemoved
+const (
+	hallEventOnline     = "online"
+	hallEventTournament = "tournament"
+)
+
+const (
+	maxHallEvents     = 1 << 10
+	hallPollTimeout   = 10 * time.Second
+	hallCheckInterval = 5 * time.Second
+)
+
+// the clients subscribe the hall by SubscribeHall, instead of polling GetNormalHall
+var hallFeed = queue.NewFeed(maxHallEvents)
+
+func initHallEvents() {
+	normalHall.SetListener(func(event string, tid int, t *types.Table) {
+		e := map[string]interface{}{"type": event, "table_id": tid}
+		if t != nil {
+			e["table"] = t.WrapTable()
+		}
+		hallFeed.Append(e)
+	})
+	go checkHallStatus()
+}
+
+// the online users and the tournament do not inform changes, check them periodically
+func checkHallStatus() {
+	defer utils.RecoverFromPanic("check hall status panic: ", log.Critical, checkHallStatus)
+	online, stat, candidates := -1, "", -1
+	for {
+		if n := session.NumOfOnlineUsers(); n != online {
+			online = n
+			hallFeed.Append(map[string]interface{}{"type": hallEventOnline, "count": n})
+		}
+		if th := tournamentHall; th != nil {
+			w := th.Wrap(0, 1, false)
+			if s, c := w["status"].(string), w["currNumCandidate"].(int); s != stat || c != candidates {
+				stat, candidates = s, c
+				delete(w, "tables")
+				w["type"] = hallEventTournament
+				hallFeed.Append(w)
+			}
+		}
+		time.Sleep(hallCheckInterval)
+	}
+}
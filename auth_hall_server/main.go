@@ -7,6 +7,7 @@ func init() {
 	initClient()
 	initDatabase()
 	initSession()
+	initRateLimit()
 	initPubServer()
 	initPrivServer()
 	initTournamentServer()
@@ -14,6 +15,7 @@ func init() {
 	initBitcoin()
 	initQueue()
 	initHall()
+	initHallEvents()
 	initGraceful()
 }
 
@@ -96,6 +96,11 @@ func (tournamentStub) Add(numCandidate, awardGold, awardSilver int, sponsor stri
 
 func (tournamentStub) Delete() { nextTournaments.Delete() }
 
+// the rate limit metrics of the public rpc of the hall and of each game server
+func (tournamentStub) RateLimitMetrics() map[string]interface{} {
+	return map[string]interface{}{"hall": pubRateLimiter.Stats(), "gameServers": clients.RateLimitMetrics()}
+}
+
 func (tournamentFilter) InputFilter(data []byte, ctx interface{}) []byte {
 	return xxtea.Decrypt(data, tournamentKey)
 }
@@ -2,12 +2,11 @@
 package main
 
 import (
-	"net/http"
+	"fmt"
 	"reflect"
 
 	"github.com/gogames/go_tetris/utils"
 	"github.com/hprose/hprose-go/hprose"
-	"github.com/xxtea/xxtea-go/xxtea"
 )
 
 var (
@@ -15,13 +14,16 @@ var (
 )
 
 type (
-	privStub   struct{}
-	privSe     struct{}
-	privFilter struct{}
+	privStub struct{}
+	privSe   struct{}
 )
 
 func (privSe) OnBeforeInvoke(funcN string, params []reflect.Value, isSimple bool, ctx interface{}) {
 	log.Info(utils.HproseLog(funcN, params, ctx))
+	// the request is signed by the key of the game server, which should be at its ip in the allowlist
+	if id, ip := utils.PrivIdOf(ctx), utils.GetIp(ctx); id == "" || privIps[id] != ip {
+		panic(fmt.Errorf("game server %q is not allowed from %s", id, ip))
+	}
 	if !pubServerEnable && funcN == "Register" {
 		panic("the auth server is closing, do not accept any registration...")
 	}
@@ -40,23 +42,17 @@ func (privSe) OnAfterInvoke(string, []reflect.Value, bool, []reflect.Value, inte
 
 func (privSe) OnSendError(error, interface{}) {}
 
-func (privFilter) InputFilter(data []byte, ctx interface{}) []byte {
-	return xxtea.Decrypt(data, privKey)
-}
-
-func (privFilter) OutputFilter(data []byte, ctx interface{}) []byte {
-	return xxtea.Encrypt(data, privKey)
-}
-
 func initPrivServer() {
 	httpPrivServer.AddMethods(privStub{})
 	httpPrivServer.ServiceEvent = privSe{}
-	httpPrivServer.SetFilter(privFilter{})
+	filter := utils.NewPrivServerFilter(privKeys)
+	filter.Reject = func(err error) { log.Warn("reject the private rpc request: %v", err) }
+	httpPrivServer.SetFilter(filter)
 	go servePrivHttp()
 }
 
 func servePrivHttp() {
-	if err := http.ListenAndServe(":"+privRpcPort, httpPrivServer); err != nil {
+	if err := utils.ServePriv(":"+privRpcPort, tlsCert, tlsKey, httpPrivServer); err != nil {
 		panic(err)
 	}
 }
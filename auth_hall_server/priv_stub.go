@@ -3,15 +3,17 @@ package main
 import (
 	"fmt"
 
+	"github.com/gogames/go_tetris/errcode"
 	"github.com/gogames/go_tetris/types"
 	"github.com/gogames/go_tetris/utils"
 )
 
-var errInsufficientEnergy = fmt.Errorf("能量不足, 每局游戏需消耗 1 能量")
+var errInsufficientEnergy = errcode.New(errcode.InsufficientEnergy)
 
-// register a game server
+// register a game server, the requests to it are signed by its key
 func (privStub) Register(maxConn int, ctx interface{}) {
-	clients.NewGameServer(utils.GetIp(ctx), maxConn)
+	id := utils.PrivIdOf(ctx)
+	clients.NewGameServer(utils.GetIp(ctx), maxConn, utils.NewPrivClientFilter(id, privKeys[id]))
 }
 
 // deactivate a game server
@@ -29,15 +31,23 @@ func (privStub) Join(tid, uid int, isOb bool) {
 	t := normalHall.GetTableById(tid)
 	u := getUserById(uid)
 	if t == nil {
-		panic(fmt.Errorf(errTableNotExist, tid))
+		panic(errcode.New(errcode.TableNotExist, tid))
 	}
 	if u == nil {
-		panic(fmt.Errorf(errUserNotExist, uid))
+		panic(errcode.New(errcode.UserNotExist, uid))
 	}
 	// check busy
 	if users.IsBusyUser(uid) {
 		panic(errAlreadyInGame)
 	}
+	// check if the owner bans the user
+	if t.IsBanned(uid) {
+		panic(types.ErrBanned)
+	}
+	// check private table, the user should be admitted when getting the token
+	if !t.IsAllowed(uid) {
+		panic(types.ErrPrivateTable)
+	}
 	// check energy
 	if u.GetEnergy() <= 0 {
 		panic(errInsufficientEnergy)
@@ -65,7 +75,7 @@ func (privStub) Join(tid, uid int, isOb bool) {
 func (privStub) ObTournament(tid, uid int) {
 	u := getUserById(uid)
 	if u == nil {
-		panic(fmt.Errorf(errUserNotExist, uid))
+		panic(errcode.New(errcode.UserNotExist, uid))
 	}
 	if err := tournamentHall.JoinTable(tid, u, true); err != nil {
 		panic(err)
@@ -75,6 +85,21 @@ func (privStub) ObTournament(tid, uid int) {
 
 // set normal game result
 func (privStub) SetNormalGameResult(tid, winner, loser int, ctx interface{}) {
+	setNormalGameResult(tid, winner, loser, false, utils.GetIp(ctx))
+}
+
+// the loser is disconnected or idle for too long, see the liveness of the game server
+func (privStub) SetForfeitResult(tid, winner, loser int, reason string, ctx interface{}) {
+	if isTournament(tid) {
+		privStub{}.SetTournamentResult(tid, winner, loser)
+	} else {
+		setNormalGameResult(tid, winner, loser, true, utils.GetIp(ctx))
+	}
+	pushFunc(func() { insertForfeit(tid, winner, loser, reason) })
+}
+
+// forfeit ends the series, and settles the frozen bets
+func setNormalGameResult(tid, winner, loser int, forfeit bool, ip string) {
 	t := normalHall.GetTableById(tid)
 	if t == nil {
 		log.Debug("the normal table %d does not exist, why set its result?", tid)
@@ -86,12 +111,19 @@ func (privStub) SetNormalGameResult(tid, winner, loser int, ctx interface{}) {
 	}
 	t.Stop()
 
+	// the loser quits during the game, the series is forfeited
+	over := t.RecordSeriesWin(winner, forfeit || !t.IsUserExist(loser))
+	// the bets of an unfinished series are still frozen
+	settled := over || t.GetSettings().Settle == types.SettlePerGame
+
 	// update winner info
 	func() {
 		w := getUserById(winner)
 		upts := make([]types.UpdateInterface, 0)
-		upts = append(upts, types.NewUpdateInt(types.UF_Balance, w.GetBalance()+t.GetBet()*2))
-		upts = append(upts, types.NewUpdateInt(types.UF_Freezed, w.GetFreezed()-t.GetBet()))
+		if settled {
+			upts = append(upts, types.NewUpdateInt(types.UF_Balance, w.GetBalance()+t.GetBet()*2))
+			upts = append(upts, types.NewUpdateInt(types.UF_Freezed, w.GetFreezed()-t.GetBet()))
+		}
 		upts = append(upts, types.NewUpdateInt(types.UF_Win, w.Win+1))
 		if (w.Win + 1) > (w.Level * w.Level) {
 			upts = append(upts, types.NewUpdateInt(types.UF_Level, w.Level+1))
@@ -105,8 +137,11 @@ func (privStub) SetNormalGameResult(tid, winner, loser int, ctx interface{}) {
 	// update loser info
 	func() {
 		l := getUserById(loser)
-		if err := l.Update(types.NewUpdateInt(types.UF_Freezed, l.GetFreezed()-t.GetBet()),
-			types.NewUpdateInt(types.UF_Lose, l.Lose+1)); err != nil {
+		upts := []types.UpdateInterface{types.NewUpdateInt(types.UF_Lose, l.Lose+1)}
+		if settled {
+			upts = append(upts, types.NewUpdateInt(types.UF_Freezed, l.GetFreezed()-t.GetBet()))
+		}
+		if err := l.Update(upts...); err != nil {
 			log.Critical("set normal hall game result, can not update loser %v: %v", l.Nickname, err)
 		}
 		pushFunc(func() { insertOrUpdateUser(l) })
@@ -114,10 +149,27 @@ func (privStub) SetNormalGameResult(tid, winner, loser int, ctx interface{}) {
 
 	// update busy timestamp
 	users.SetBusy(t.GetAllUsers()...)
+	// the levels may change
+	normalHall.Reindex(tid)
 
-	if err := clients.GetStub(utils.GetIp(ctx)).SetNormalGameResult(tid, winner, t.GetBet()); err != nil {
+	bet := 0
+	if settled {
+		bet = t.GetBet()
+	}
+	if err := clients.GetStub(ip).SetNormalGameResult(tid, winner, bet); err != nil {
 		log.Warn("can not inform game server to set the game result: %v", err)
 	}
+	informSeries(t, ip)
+}
+
+// inform the game server the score of the series
+func informSeries(t *types.Table, ip string) {
+	if t.GetSettings().Series <= 1 {
+		return
+	}
+	if err := clients.GetStub(ip).SetSeries(t.GetTid(), t.WrapSeries()); err != nil {
+		log.Warn("can not inform game server the series of table %d: %v", t.GetTid(), err)
+	}
 }
 
 // set tournament game result
@@ -209,6 +261,10 @@ func (privStub) Quit(tid, uid int, isTournament bool) {
 			log.Debug("why the table %d is nil but also quit? because of gracefully?", tid)
 			return
 		}
+		// quit between the games of a series, see SetNormalGameResult for quitting during a game
+		if !t.IsStart() && t.IsSeriesBetFrozen() {
+			forfeitSeries(t, uid)
+		}
 		t.Quit(uid)
 		if t.HasNoPlayer() {
 			normalHall.DelTable(tid)
@@ -217,6 +273,32 @@ func (privStub) Quit(tid, uid int, isTournament bool) {
 	users.SetFree(uid)
 }
 
+// the player quits in the middle of a series, the opponent takes the frozen bets
+func forfeitSeries(t *types.Table, quitter int) {
+	var winner int
+	switch quitter {
+	case t.Get1pUid():
+		winner = t.Get2pUid()
+	case t.Get2pUid():
+		winner = t.Get1pUid()
+	default:
+		return
+	}
+	w, l, bet := getUserById(winner), getUserById(quitter), t.GetBet()
+	if w == nil || l == nil {
+		log.Critical("nil user? can not settle the series of table %d", t.GetTid())
+		return
+	}
+	if err := w.Update(types.NewUpdateInt(types.UF_Balance, w.GetBalance()+bet*2),
+		types.NewUpdateInt(types.UF_Freezed, w.GetFreezed()-bet)); err != nil {
+		log.Critical("can not settle the forfeited series, winner %v: %v", w.Nickname, err)
+	}
+	if err := l.Update(types.NewUpdateInt(types.UF_Freezed, l.GetFreezed()-bet)); err != nil {
+		log.Critical("can not settle the forfeited series, loser %v: %v", l.Nickname, err)
+	}
+	pushFunc(func() { insertOrUpdateUser(w, l) })
+}
+
 var errTournamentDefaultReady = fmt.Errorf("tournament default is ready and can not set to not ready, what is wrong?")
 
 // switch ready state
@@ -236,12 +318,13 @@ func (privStub) SwitchReady(tid, uid int, ctx interface{}) {
 	u := getUserById(uid)
 	if u == nil {
 		log.Debug("the user %d is not exist", uid)
-		panic(fmt.Errorf(errUserNotExist, uid))
+		panic(errcode.New(errcode.UserNotExist, uid))
 	}
 	if u.GetEnergy() <= 0 {
 		panic(errInsufficientEnergy)
 	}
-	if u.GetBalance() < t.GetBet() {
+	// the bet of the series is already frozen
+	if !t.IsSeriesBetFrozen() && u.GetBalance() < t.GetBet() {
 		panic(errBalNotSufficient)
 	}
 	t.SwitchReady(uid)
@@ -262,7 +345,9 @@ func tableChecker(t *types.Table, ctx interface{}) {
 		log.Critical("can not inform game server to start the table %d", tid)
 		return
 	}
+	frozen := t.IsSeriesBetFrozen()
 	t.Start()
+	informSeries(t, utils.GetIp(ctx))
 
 	u1p := getUserById(t.Get1pUid())
 	u2p := getUserById(t.Get2pUid())
@@ -283,8 +368,8 @@ func tableChecker(t *types.Table, ctx interface{}) {
 		log.Critical("can not update energy: %v", err)
 	}
 
-	// update balance
-	if bet := t.GetBet(); bet > 0 {
+	// update balance, once for a series settled per series
+	if bet := t.GetBet(); bet > 0 && !frozen {
 		b1p, b2p := u1p.GetBalance(), u2p.GetBalance()
 		f1p, f2p := u1p.GetFreezed(), u2p.GetFreezed()
 		if b1p < bet || b2p < bet {
@@ -4,16 +4,16 @@ import (
 	"net/http"
 	"reflect"
 
+	"github.com/gogames/go_tetris/errcode"
 	"github.com/gogames/go_tetris/utils"
 	"github.com/hprose/hprose-go/hprose"
 )
 
 var (
-	pubServerFrequencyLimit = utils.NewFrequency(120, 5) // request to rpc server can not exceeds 120 times per minute
-	httpPubServer           = hprose.NewHttpService()
-	errCreateSessionFirst   = "请先创建session"
-	errClosingServer        = "we are closing the server, not accept request at the moment"
-	pubServerEnable         = true
+	httpPubServer         = hprose.NewHttpService()
+	errCreateSessionFirst = errcode.New(errcode.CreateSessionFirst)
+	errClosingServer      = errcode.New(errcode.ServerClosing)
+	pubServerEnable       = true
 )
 
 type (
@@ -22,14 +22,23 @@ type (
 )
 
 func (pubSe) OnBeforeInvoke(fName string, params []reflect.Value, isSimple bool, ctx interface{}) {
+	// the errors are in the language of the session
+	if l := len(params); l > 0 {
+		if lang, ok := session.GetSession(sessKeyLang, params[l-1].String()).(string); ok {
+			utils.SetRequestLanguage(ctx, lang)
+		}
+	}
+
 	if !pubServerEnable {
 		panic(errClosingServer)
 	}
 
 	if !*debug {
-		if err := pubServerFrequencyLimit.Incr(utils.GetIp(ctx)); err != nil {
-			panic(err)
+		user := ""
+		if l := len(params); l > 0 {
+			user = rateLimitUser(params[l-1].String())
 		}
+		utils.TakeToken(pubRateLimiter, fName, user, ctx)
 	}
 
 	log.Info(utils.HproseLog(fName, params, ctx))
@@ -49,13 +58,15 @@ func initPubServer() {
 	httpPubServer.DebugEnabled = *debug
 	httpPubServer.AddMethods(pubStub{})
 	httpPubServer.ServiceEvent = pubSe{}
+	httpPubServer.SetFilter(utils.ErrorFilter{})
 	httpPubServer.CrossDomainEnabled = true
 	httpPubServer.SetCrossDomainXmlFile(crossDomainFile)
+	initRestGateway()
 	go servePubHttp()
 }
 
 func servePubHttp() {
-	if err := http.ListenAndServe(":"+pubRpcPort, httpPubServer); err != nil {
+	if err := http.ListenAndServe(":"+pubRpcPort, pubHandler()); err != nil {
 		panic(err)
 	}
 }
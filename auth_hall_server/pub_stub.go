@@ -1,10 +1,10 @@
 package main
 
 import (
-	"fmt"
 	"math"
 	"regexp"
 
+	"github.com/gogames/go_tetris/errcode"
 	"github.com/gogames/go_tetris/types"
 	"github.com/gogames/go_tetris/utils"
 )
@@ -14,17 +14,12 @@ const (
 	sessKeyRegister   = "register"
 	sessKeyForgetPass = "forget"
 	sessKeyEmail      = "email"
+	sessKeyLang       = "lang" // the language of the errors, see SetLanguage
 	minWithdraw       = 1
 	minEnergy         = 1
 	ratioEnergy2mBTC  = 10
 	maxAvatar         = 1 << 18 // 256KB
 	defaultEnergy     = 10
-
-	// error const
-	errUserNotExist    = "用户 %v 不存在"
-	errTableNotExist   = "桌子 %v 不存在, 请加入别的桌子"
-	errUnmatchedEmail  = "验证码发到邮箱 %s, 注册邮箱也必须是这个! 请不要这样攻击我们的服务!"
-	errExceedMaxAvatar = "头像大小不能超过256KB, 该头像大小为 %dKB."
 )
 
 var (
@@ -34,32 +29,32 @@ var (
 	nickReg  = regexp.MustCompile(`^(\p{Han}|\w){2,8}$`)
 
 	// errors
-	errEncryptPassword           = fmt.Errorf("密码哈希错误")
-	errIncorrectPwd              = fmt.Errorf("密码错误")
-	errIncorrectEmailFormat      = fmt.Errorf("邮箱格式错误")
-	errIncorrectNicknameFormat   = fmt.Errorf("昵称格式错误, 必须由2~8个中文,英文,数字组成")
-	errIncorrectPasswordFormat   = fmt.Errorf("密码格式错误, 必须由6~22个英文,数字组成")
-	errEmailExist                = fmt.Errorf("邮箱已经被占用")
-	errNicknameExist             = fmt.Errorf("昵称已经被占用")
-	errNotLoggedIn               = fmt.Errorf("请先登陆")
-	errBalNotSufficient          = fmt.Errorf("余额不足")
-	errInvalidBtcAddr            = fmt.Errorf("无效的比特币地址")
-	errExceedMinWithdraw         = fmt.Errorf("最低提现额度是 1mBTC")
-	errExceedMinEnergy           = fmt.Errorf("最低购买1mBTC能量, 每1mBTC可以充%v能量", ratioEnergy2mBTC)
-	errRegisterGetCodeFirst      = fmt.Errorf("需要先发送验证码到邮箱, 才能完成注册")
-	errRegisterIncorrectCode     = fmt.Errorf("验证码错误, 请检查邮箱")
-	errForgetPassGetCodeFirst    = fmt.Errorf("需要先发送验证码到邮箱, 才能找回密码")
-	errForgetPassIncorrectCode   = fmt.Errorf("验证码错误, 请检查邮箱")
-	errTableGameIsStarted        = fmt.Errorf("游戏已经开始, 请加入别的桌子")
-	errTableIsFull               = fmt.Errorf("桌子已满, 请加入别的桌子进行游戏")
-	errUnmatchNumOfFieldAndVal   = fmt.Errorf("两个数组长度不一样")
-	errIncorrectType             = fmt.Errorf("更新字段类型只能是字符串, 整形, 二进制流[]byte")
-	errAlreadyInGame             = fmt.Errorf("你已经在游戏中, 请先退出再加入另一个游戏")
-	errNegativeBet               = fmt.Errorf("赌注不能为负数")
-	errCantApplyForNilTournament = fmt.Errorf("暂无争霸赛, 无法加入.")
-	errNilTournamentHall         = fmt.Errorf("暂无争霸赛, 无法获得争霸赛桌子信息")
-	errCantMatchOpponent         = fmt.Errorf("无匹配对手, 请稍后重试.")
-	errNoWorkingGameServer       = fmt.Errorf("当前没有游戏服务器工作")
+	errEncryptPassword           = errcode.New(errcode.EncryptPassword)
+	errIncorrectPwd              = errcode.New(errcode.IncorrectPwd)
+	errIncorrectEmailFormat      = errcode.New(errcode.IncorrectEmailFormat)
+	errIncorrectNicknameFormat   = errcode.New(errcode.IncorrectNicknameFormat)
+	errIncorrectPasswordFormat   = errcode.New(errcode.IncorrectPasswordFormat)
+	errEmailExist                = errcode.New(errcode.EmailExist)
+	errNicknameExist             = errcode.New(errcode.NicknameExist)
+	errNotLoggedIn               = errcode.New(errcode.NotLoggedIn)
+	errBalNotSufficient          = errcode.New(errcode.BalNotSufficient)
+	errInvalidBtcAddr            = errcode.New(errcode.InvalidBtcAddr)
+	errExceedMinWithdraw         = errcode.New(errcode.ExceedMinWithdraw, minWithdraw)
+	errExceedMinEnergy           = errcode.New(errcode.ExceedMinEnergy, minEnergy, ratioEnergy2mBTC)
+	errRegisterGetCodeFirst      = errcode.New(errcode.RegisterGetCodeFirst)
+	errRegisterIncorrectCode     = errcode.New(errcode.RegisterIncorrectCode)
+	errForgetPassGetCodeFirst    = errcode.New(errcode.ForgetPassGetCodeFirst)
+	errForgetPassIncorrectCode   = errcode.New(errcode.ForgetPassIncorrectCode)
+	errTableGameIsStarted        = errcode.New(errcode.TableGameIsStarted)
+	errTableIsFull               = errcode.New(errcode.TableIsFull)
+	errUnmatchNumOfFieldAndVal   = errcode.New(errcode.UnmatchNumOfFieldAndVal)
+	errIncorrectType             = errcode.New(errcode.IncorrectType)
+	errAlreadyInGame             = errcode.New(errcode.AlreadyInGame)
+	errNegativeBet               = errcode.New(errcode.NegativeBet)
+	errCantApplyForNilTournament = errcode.New(errcode.NilTournament)
+	errNilTournamentHall         = errcode.New(errcode.NilTournamentHall)
+	errCantMatchOpponent         = errcode.New(errcode.CantMatchOpponent)
+	errNoWorkingGameServer       = errcode.New(errcode.NoWorkingGameServer)
 )
 
 // create session and return session id
@@ -67,6 +62,15 @@ func (pubStub) CreateSession() string {
 	return session.CreateSession()
 }
 
+// the language of the errors, "zh-CN" or "en", Accept-Language of the request by default
+func (pubStub) SetLanguage(lang string, sessId string) {
+	lang, err := errcode.ParseLanguage(lang)
+	if err != nil {
+		panic(err)
+	}
+	session.SetSession(sessKeyLang, lang, sessId)
+}
+
 // send mail, register auth
 func (pubStub) SendMailRegister(to string, sessId string) {
 	if !emailReg.MatchString(to) {
@@ -95,7 +99,7 @@ func (pubStub) SendMailForget(to string, sessId string) {
 		panic(errIncorrectEmailFormat)
 	}
 	if u := getUserByEmail(to); u == nil {
-		panic(fmt.Errorf(errUserNotExist, to))
+		panic(errcode.New(errcode.UserNotExist, to))
 	}
 	authenCode := utils.RandString(8)
 	session.SetSession(sessKeyForgetPass, authenCode, sessId)
@@ -130,7 +134,7 @@ func (pubStub) ForgetPassword(newPassword, authenCode string, sessId string) {
 	}
 	u := getUserByEmail(email)
 	if u == nil {
-		panic(fmt.Errorf(errUserNotExist, email))
+		panic(errcode.New(errcode.UserNotExist, email))
 	}
 	if err := u.Update(types.NewUpdateString(types.UF_Password, utils.Encrypt(newPassword))); err != nil {
 		panic(err)
@@ -153,7 +157,7 @@ func (pubStub) Register(email, password, nickname, authenCode string, sessId str
 	if tmpEmail, ok := session.GetSession(sessKeyEmail, sessId).(string); !ok {
 		panic(errRegisterGetCodeFirst)
 	} else if email != tmpEmail {
-		panic(fmt.Errorf(errUnmatchedEmail, tmpEmail))
+		panic(errcode.New(errcode.UnmatchedEmail, tmpEmail))
 	}
 
 	// check input
@@ -197,7 +201,7 @@ func (pubStub) Register(email, password, nickname, authenCode string, sessId str
 func (pubStub) Login(nickname, password string, sessId string) {
 	u := getUserByNickname(nickname)
 	if u == nil {
-		panic(fmt.Errorf(errUserNotExist, nickname))
+		panic(errcode.New(errcode.UserNotExist, nickname))
 	}
 	if u.Password != utils.Encrypt(password) {
 		panic(errIncorrectPwd)
@@ -220,7 +224,7 @@ func (pubStub) GetUserInfo(sessId string) map[string]interface{} {
 	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
 		u := getUserById(uid)
 		if u == nil {
-			panic(fmt.Errorf(errUserNotExist, uid))
+			panic(errcode.New(errcode.UserNotExist, uid))
 		}
 		return u.Wrap()
 	}
@@ -250,7 +254,7 @@ func (pubStub) UpdateUserPassword(currPass, newPass string, sessId string) {
 // update user avatar
 func (pubStub) UpdateUserAvatar(avatar []byte, sessId string) {
 	if l := len(avatar); l > maxAvatar {
-		panic(fmt.Errorf(errExceedMaxAvatar, l))
+		panic(errcode.New(errcode.ExceedMaxAvatar, l>>10))
 	}
 	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
 		u := getUserById(uid)
@@ -269,19 +273,40 @@ func (pubStub) GetNormalHall(numTableInPage, pageNum int, filterWait bool, sessI
 	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
 		u := getUserById(uid)
 		if u == nil {
-			panic(fmt.Errorf(errUserNotExist, uid))
+			panic(errcode.New(errcode.UserNotExist, uid))
 		}
 		return normalHall.Wrap(numTableInPage, pageNum, filterWait)
 	}
 	panic(errNotLoggedIn)
 }
 
+// search the normal hall, return the tables and the cursor of the next page
+// see types.ParseTableQuery for the query keys
+func (pubStub) SearchTables(query map[string]interface{}, sessId string) ([]map[string]interface{}, string) {
+	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
+		u := getUserById(uid)
+		if u == nil {
+			panic(errcode.New(errcode.UserNotExist, uid))
+		}
+		q, err := types.ParseTableQuery(query)
+		if err != nil {
+			panic(err)
+		}
+		tables, cursor, err := normalHall.Search(q)
+		if err != nil {
+			panic(err)
+		}
+		return tables, cursor
+	}
+	panic(errNotLoggedIn)
+}
+
 // get tournament hall
 func (pubStub) GetTournamentHall(numTableInPage, pageNum int, filterWait bool, sessId string) map[string]interface{} {
 	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
 		u := getUserById(uid)
 		if u == nil {
-			panic(fmt.Errorf(errUserNotExist, uid))
+			panic(errcode.New(errcode.UserNotExist, uid))
 		}
 		return tournamentHall.Wrap(numTableInPage, pageNum, filterWait)
 	}
@@ -293,7 +318,7 @@ func (pubStub) GetNormalTable(tid int, sessId string) map[string]interface{} {
 	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
 		u := getUserById(uid)
 		if u == nil {
-			panic(fmt.Errorf(errUserNotExist, uid))
+			panic(errcode.New(errcode.UserNotExist, uid))
 		}
 		return normalHall.GetTableById(tid).WrapTable()
 	}
@@ -305,7 +330,7 @@ func (pubStub) GetTournamentTable(tid int, sessId string) map[string]interface{}
 	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
 		u := getUserById(uid)
 		if u == nil {
-			panic(fmt.Errorf(errUserNotExist, uid))
+			panic(errcode.New(errcode.UserNotExist, uid))
 		}
 		if tournamentHall == nil {
 			panic(errNilTournamentHall)
@@ -317,18 +342,23 @@ func (pubStub) GetTournamentTable(tid int, sessId string) map[string]interface{}
 
 // join a normal game, play or observe
 // actually it is just get a token
-func (pubStub) Join(tid int, isOb bool, sessId string) string {
+func (p pubStub) Join(tid int, isOb bool, sessId string) string {
+	return p.JoinWithSecret(tid, isOb, "", sessId)
+}
+
+// join a private game, the secret is the password or an invite token
+func (pubStub) JoinWithSecret(tid int, isOb bool, secret string, sessId string) string {
 	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
 		u := getUserById(uid)
 		if u == nil {
-			panic(fmt.Errorf(errUserNotExist, uid))
+			panic(errcode.New(errcode.UserNotExist, uid))
 		}
 		if users.IsBusyUser(uid) {
 			panic(errAlreadyInGame)
 		}
 		t := normalHall.GetTableById(tid)
 		if t == nil {
-			panic(fmt.Errorf(errTableNotExist, tid))
+			panic(errcode.New(errcode.TableNotExist, tid))
 		}
 		if u.GetBalance() < t.GetBet() {
 			panic(errBalNotSufficient)
@@ -336,6 +366,9 @@ func (pubStub) Join(tid int, isOb bool, sessId string) string {
 		if u.GetEnergy() <= 0 {
 			panic(errInsufficientEnergy)
 		}
+		if t.IsBanned(uid) {
+			panic(types.ErrBanned)
+		}
 		if !isOb {
 			if t.IsStart() {
 				panic(errTableGameIsStarted)
@@ -344,7 +377,11 @@ func (pubStub) Join(tid int, isOb bool, sessId string) string {
 				panic(errTableIsFull)
 			}
 		}
-		token, err := utils.GenerateToken(uid, u.Nickname, false, isOb, tid)
+		// the last check, an invite is used up only by a join which is not rejected
+		if err := t.Admit(uid, secret, hashTablePassword(secret)); err != nil {
+			panic(err)
+		}
+		token, err := utils.GenerateToken(uid, u.Nickname, false, isOb, tid, t.GetHost())
 		if err != nil {
 			panic(err)
 		}
@@ -364,16 +401,16 @@ func (pubStub) ObserveTournament(tid int, sessId string) string {
 	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
 		u := getUserById(uid)
 		if u == nil {
-			panic(fmt.Errorf(errUserNotExist, uid))
+			panic(errcode.New(errcode.UserNotExist, uid))
 		}
 		if users.IsBusyUser(uid) {
 			panic(errAlreadyInGame)
 		}
 		t := tournamentHall.GetTableById(tid)
 		if t == nil {
-			panic(fmt.Errorf(errTableNotExist, tid))
+			panic(errcode.New(errcode.TableNotExist, tid))
 		}
-		token, err := utils.GenerateToken(uid, u.Nickname, false, true, tid)
+		token, err := utils.GenerateToken(uid, u.Nickname, false, true, tid, t.GetHost())
 		if err != nil {
 			panic(err)
 		}
@@ -388,7 +425,7 @@ func (pubStub) AutoMatch(sessId string) (string, string) {
 	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
 		u := getUserById(uid)
 		if u == nil {
-			panic(fmt.Errorf(errUserNotExist, uid))
+			panic(errcode.New(errcode.UserNotExist, uid))
 		}
 		if users.IsBusyUser(uid) {
 			panic(errAlreadyInGame)
@@ -399,7 +436,7 @@ func (pubStub) AutoMatch(sessId string) (string, string) {
 		var table *types.Table = nil
 		var gap float64 = 100
 		for _, t := range normalHall.Tables.Tables {
-			if t.IsFull() {
+			if t.IsFull() || t.IsPrivate() || t.IsBanned(uid) {
 				continue
 			}
 			if u.GetBalance() < t.GetBet() {
@@ -429,7 +466,7 @@ func (pubStub) AutoMatch(sessId string) (string, string) {
 		if table == nil {
 			panic(errCantMatchOpponent)
 		}
-		token, err := utils.GenerateToken(uid, u.Nickname, false, false, table.GetTid())
+		token, err := utils.GenerateToken(uid, u.Nickname, false, false, table.GetTid(), table.GetHost())
 		if err != nil {
 			panic(err)
 		}
@@ -440,14 +477,33 @@ func (pubStub) AutoMatch(sessId string) (string, string) {
 }
 
 // create a game
-func (pubStub) Create(title string, bet int, sessId string) int {
+func (p pubStub) Create(title string, bet int, sessId string) int {
+	return p.CreateWithSettings(title, bet, nil, sessId)
+}
+
+// create a game with the settings, the missing settings are default
+func (pubStub) CreateWithSettings(title string, bet int, settings map[string]int, sessId string) int {
+	return createNormalTable(title, bet, settings, false, "", sessId)
+}
+
+// create a private game, only the users with the password or an invite can join or observe it
+// the password can be empty, then the table can only be joined by invites
+func (pubStub) CreatePrivate(title string, bet int, settings map[string]int, password string, sessId string) int {
+	return createNormalTable(title, bet, settings, true, password, sessId)
+}
+
+func createNormalTable(title string, bet int, settings map[string]int, private bool, password string, sessId string) int {
 	if bet < 0 {
 		panic(errNegativeBet)
 	}
+	ts, err := types.ParseTableSettings(settings)
+	if err != nil {
+		panic(err)
+	}
 	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
 		u := getUserById(uid)
 		if u == nil {
-			panic(fmt.Errorf(errUserNotExist, uid))
+			panic(errcode.New(errcode.UserNotExist, uid))
 		}
 		if users.IsBusyUser(uid) {
 			panic(errAlreadyInGame)
@@ -464,10 +520,15 @@ func (pubStub) Create(title string, bet int, sessId string) int {
 			panic(errNoWorkingGameServer)
 		}
 		host := constructHost(ip)
-		if err := clients.GetStub(ip).Create(id); err != nil {
+		if err := clients.GetStub(ip).Create(id, ts.Wrap()); err != nil {
 			panic(err)
 		}
-		if err := normalHall.NewTable(id, title, host, bet); err != nil {
+		if private {
+			err = normalHall.NewPrivateTable(id, title, host, bet, ts, uid, hashTablePassword(password))
+		} else {
+			err = normalHall.NewOwnedTable(id, title, host, bet, ts, uid)
+		}
+		if err != nil {
 			panic(err)
 		}
 		return id
@@ -475,6 +536,136 @@ func (pubStub) Create(title string, bet int, sessId string) int {
 	panic(errNotLoggedIn)
 }
 
+// the owner rotates the password of a private table
+func (pubStub) SetTablePassword(tid int, password string, sessId string) {
+	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
+		t := normalHall.GetTableById(tid)
+		if t == nil {
+			panic(errcode.New(errcode.TableNotExist, tid))
+		}
+		if err := t.SetPassword(uid, hashTablePassword(password)); err != nil {
+			panic(err)
+		}
+		session.SetSession(sessKeyUserId, uid, sessId)
+		return
+	}
+	panic(errNotLoggedIn)
+}
+
+// the owner gets a one-time invite token of a private table
+func (pubStub) CreateInvite(tid int, sessId string) string {
+	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
+		t := normalHall.GetTableById(tid)
+		if t == nil {
+			panic(errcode.New(errcode.TableNotExist, tid))
+		}
+		invite := utils.RandString(16)
+		if err := t.AddInvite(uid, invite); err != nil {
+			panic(err)
+		}
+		session.SetSession(sessKeyUserId, uid, sessId)
+		return invite
+	}
+	panic(errNotLoggedIn)
+}
+
+// the owner kicks a player or an observer out of the table
+// if ban, the user can not join or observe the table again
+func (pubStub) Kick(tid, uid int, ban bool, sessId string) {
+	if owner, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
+		t := normalHall.GetTableById(tid)
+		if t == nil {
+			panic(errcode.New(errcode.TableNotExist, tid))
+		}
+		if err := t.CanKick(owner, uid); err != nil {
+			panic(err)
+		}
+		inTable := t.IsUserExist(uid)
+		if !inTable && !ban {
+			panic(types.ErrNotInTable)
+		}
+		if ban {
+			if err := t.Ban(owner, uid); err != nil {
+				panic(err)
+			}
+		}
+		// the game server quits the user, then informs the auth server
+		if inTable {
+			if err := clients.GetStub(t.GetIp()).Kick(tid, uid, ban); err != nil {
+				panic(err)
+			}
+		}
+		session.SetSession(sessKeyUserId, owner, sessId)
+		return
+	}
+	panic(errNotLoggedIn)
+}
+
+// the owner locks or unlocks the second seat
+func (pubStub) LockSeat(tid int, locked bool, sessId string) {
+	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
+		t := normalHall.GetTableById(tid)
+		if t == nil {
+			panic(errcode.New(errcode.TableNotExist, tid))
+		}
+		if err := t.LockSeat(uid, locked); err != nil {
+			panic(err)
+		}
+		if err := clients.GetStub(t.GetIp()).LockSeat(tid, locked); err != nil {
+			panic(err)
+		}
+		session.SetSession(sessKeyUserId, uid, sessId)
+		return
+	}
+	panic(errNotLoggedIn)
+}
+
+// the owner mutes or unmutes the observers
+func (pubStub) MuteObservers(tid int, muted bool, sessId string) {
+	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
+		t := normalHall.GetTableById(tid)
+		if t == nil {
+			panic(errcode.New(errcode.TableNotExist, tid))
+		}
+		if err := t.MuteObservers(uid, muted); err != nil {
+			panic(err)
+		}
+		if err := clients.GetStub(t.GetIp()).MuteObservers(tid, muted); err != nil {
+			panic(err)
+		}
+		session.SetSession(sessKeyUserId, uid, sessId)
+		return
+	}
+	panic(errNotLoggedIn)
+}
+
+// the owner hands the table to another user in the table
+func (pubStub) TransferOwner(tid, uid int, sessId string) {
+	if owner, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
+		t := normalHall.GetTableById(tid)
+		if t == nil {
+			panic(errcode.New(errcode.TableNotExist, tid))
+		}
+		if err := t.TransferOwner(owner, uid); err != nil {
+			panic(err)
+		}
+		if err := clients.GetStub(t.GetIp()).TransferOwner(tid, uid); err != nil {
+			panic(err)
+		}
+		session.SetSession(sessKeyUserId, owner, sessId)
+		return
+	}
+	panic(errNotLoggedIn)
+}
+
+// empty password means no password
+func hashTablePassword(password string) string {
+	if password == "" {
+		return ""
+	}
+	return utils.Encrypt(password)
+}
+
 // TODO:
 // apply for a tournament
 func (pubStub) Apply(sessId string) (string, string) {
@@ -484,12 +675,12 @@ func (pubStub) Apply(sessId string) (string, string) {
 	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
 		u := getUserById(uid)
 		if u == nil {
-			panic(fmt.Errorf(errUserNotExist, uid))
+			panic(errcode.New(errcode.UserNotExist, uid))
 		}
 		if users.IsBusyUser(uid) {
 			panic(errAlreadyInGame)
 		}
-		token, err := utils.GenerateToken(uid, u.Nickname, true, false, -1)
+		token, err := utils.GenerateToken(uid, u.Nickname, true, false, -1, tournamentHall.GetHost())
 		if err != nil {
 			panic(err)
 		}
@@ -516,7 +707,7 @@ func (pubStub) Withdraw(amount int, address string, sessId string) string {
 	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
 		u := getUserById(uid)
 		if u == nil {
-			panic(fmt.Errorf(errUserNotExist, uid))
+			panic(errcode.New(errcode.UserNotExist, uid))
 		}
 		// check balance
 		if u.GetBalance() < amount {
@@ -548,7 +739,7 @@ func (pubStub) BuyEnergy(amountOfmBTC int, sessId string) {
 	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
 		u := getUserById(uid)
 		if u == nil {
-			panic(fmt.Errorf(errUserNotExist, uid))
+			panic(errcode.New(errcode.UserNotExist, uid))
 		}
 		// check balance
 		if u.GetBalance() < amountOfmBTC {
@@ -575,6 +766,17 @@ func (pubStub) NumOfOnlinePlayer() int {
 	return session.NumOfOnlineUsers()
 }
 
+// subscribe the hall from the cursor, wait for the events at most hallPollTimeout
+// the cursor should be the next cursor from the last call, -1 for the first call
+// if resync, the cursor is too old, get the hall by GetNormalHall and subscribe from the next cursor
+func (pubStub) SubscribeHall(cursor int, sessId string) (events []interface{}, next int, resync bool) {
+	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
+		session.SetSession(sessKeyUserId, uid, sessId)
+		return hallFeed.Wait(cursor, hallPollTimeout)
+	}
+	panic(errNotLoggedIn)
+}
+
 // 不需要sessionId 的函数
 var notNeedSessFunc = map[string]bool{
 	"NumOfOnlinePlayer": true,
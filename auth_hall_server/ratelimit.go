@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gogames/go_tetris/utils/ratelimit"
+)
+
+const rateMetricsInterval = 10 * time.Minute
+
+// rate limits of the public rpc, tokens a minute and the burst, by method
+// override them by rateLimits of the configuration
+var pubRateLimits = map[string]ratelimit.Policy{
+	ratelimit.Default:    {Rate: 120, Burst: 30},
+	"SubscribeHall":      {Rate: 600, Burst: 60}, // long polling
+	"CreateSession":      {Rate: 30, Burst: 10},
+	"SendMailRegister":   {Rate: 2, Burst: 2},
+	"SendMailForget":     {Rate: 2, Burst: 2},
+	"Register":           {Rate: 5, Burst: 3},
+	"ForgetPassword":     {Rate: 5, Burst: 3},
+	"Login":              {Rate: 10, Burst: 5},
+	"UpdateUserPassword": {Rate: 5, Burst: 3},
+	"Withdraw":           {Rate: 3, Burst: 2},
+	"BuyEnergy":          {Rate: 10, Burst: 3},
+}
+
+var pubRateLimiter *ratelimit.Limiter
+
+func initRateLimit() {
+	pubRateLimiter = ratelimit.New(pubRateLimits)
+	pubRateLimiter.SetPolicies(rateLimits)
+	pubRateLimiter.Allow(rateLimitAllowlist...)
+	go logRateMetrics()
+}
+
+// the logged in user is limited by uid, otherwise by ip
+func rateLimitUser(sessId string) string {
+	if uid, ok := session.GetSession(sessKeyUserId, sessId).(int); ok {
+		return fmt.Sprintf("uid:%d", uid)
+	}
+	return ""
+}
+
+func logRateMetrics() {
+	for {
+		time.Sleep(rateMetricsInterval)
+		metrics, buckets := pubRateLimiter.Metrics()
+		log.Info("rate limit of the public rpc, %d buckets: %v", buckets, metrics)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/gogames/go_tetris/utils/rest"
+)
+
+// the rest gateway is served with the public rpc, the document is at /api/openapi.json
+const restBase = "/api"
+
+// rest routes of the public rpc, the literal paths go before the params, e.g. /tables/search before /tables/{tid}
+var restRoutes = []rest.Route{
+	// sessions
+	{Method: "POST", Path: "/sessions", Stub: "CreateSession", Results: []string{"sessionId"}, Tag: "sessions", Summary: "create a session, send its id in the X-Session-Id header"},
+	{Method: "PUT", Path: "/session/language", Stub: "SetLanguage", Params: []string{"lang"}, Tag: "sessions", Summary: "the language of the errors, zh-CN or en"},
+	{Method: "POST", Path: "/session/login", Stub: "Login", Params: []string{"nickname", "password"}, Tag: "sessions", Summary: "log in"},
+	{Method: "DELETE", Path: "/session/login", Stub: "Logout", Tag: "sessions", Summary: "log out"},
+
+	// users
+	{Method: "GET", Path: "/users/online", Stub: "NumOfOnlinePlayer", Results: []string{"online"}, Tag: "users", Summary: "the number of the online players"},
+	{Method: "POST", Path: "/users/register-code", Stub: "SendMailRegister", Params: []string{"email"}, Tag: "users", Summary: "send the verification code of registering"},
+	{Method: "POST", Path: "/users", Stub: "Register", Params: []string{"email", "password", "nickname", "code"}, Tag: "users", Summary: "register"},
+	{Method: "POST", Path: "/users/password-code", Stub: "SendMailForget", Params: []string{"email"}, Tag: "users", Summary: "send the verification code of resetting the password"},
+	{Method: "POST", Path: "/users/password", Stub: "ForgetPassword", Params: []string{"newPassword", "code"}, Tag: "users", Summary: "reset the password by the verification code"},
+	{Method: "GET", Path: "/users/me", Stub: "GetUserInfo", Results: []string{"user"}, Tag: "users", Summary: "the logged in user"},
+	{Method: "PUT", Path: "/users/me/password", Stub: "UpdateUserPassword", Params: []string{"currentPassword", "newPassword"}, Tag: "users", Summary: "change the password"},
+	{Method: "PUT", Path: "/users/me/avatar", Stub: "UpdateUserAvatar", Params: []string{"avatar"}, Tag: "users", Summary: "change the avatar, 256KB at most"},
+
+	// tables
+	{Method: "GET", Path: "/tables", Stub: "GetNormalHall", Params: []string{"pageSize", "page", "filterWait?"}, Results: []string{"tables"}, Tag: "tables", Summary: "a page of the normal hall"},
+	{Method: "POST", Path: "/tables/search", Stub: "SearchTables", Params: []string{"query"}, Results: []string{"tables", "cursor"}, Tag: "tables", Summary: "search the normal hall, see types.ParseTableQuery"},
+	{Method: "GET", Path: "/tables/events", Stub: "SubscribeHall", Params: []string{"cursor"}, Results: []string{"events", "next", "resync"}, Tag: "tables", Summary: "long poll the events of the hall from the cursor"},
+	{Method: "POST", Path: "/tables/match", Stub: "AutoMatch", Results: []string{"host", "token"}, Tag: "tables", Summary: "match a table and get the token to join it"},
+	{Method: "POST", Path: "/tables", Stub: "CreateWithSettings", Params: []string{"title", "bet", "settings?"}, Results: []string{"tid"}, Tag: "tables", Summary: "create a table"},
+	{Method: "POST", Path: "/tables/private", Stub: "CreatePrivate", Params: []string{"title", "bet", "settings?", "password?"}, Results: []string{"tid"}, Tag: "tables", Summary: "create a private table"},
+	{Method: "GET", Path: "/tables/{tid}", Stub: "GetNormalTable", Params: []string{"tid"}, Results: []string{"table"}, Tag: "tables", Summary: "a normal table"},
+	{Method: "POST", Path: "/tables/{tid}/join", Stub: "JoinWithSecret", Params: []string{"tid", "observe?", "secret?"}, Results: []string{"token"}, Tag: "tables", Summary: "get the token to play or observe, the secret is the password or an invite of a private table"},
+	{Method: "PUT", Path: "/tables/{tid}/password", Stub: "SetTablePassword", Params: []string{"tid", "password"}, Tag: "tables", Summary: "change the password of a private table"},
+	{Method: "POST", Path: "/tables/{tid}/invites", Stub: "CreateInvite", Params: []string{"tid"}, Results: []string{"invite"}, Tag: "tables", Summary: "create an invite of a private table"},
+	{Method: "POST", Path: "/tables/{tid}/kick", Stub: "Kick", Params: []string{"tid", "uid", "ban?"}, Tag: "tables", Summary: "kick a player, and ban them from the table"},
+	{Method: "PUT", Path: "/tables/{tid}/seat", Stub: "LockSeat", Params: []string{"tid", "locked"}, Tag: "tables", Summary: "lock the empty seat"},
+	{Method: "PUT", Path: "/tables/{tid}/observers", Stub: "MuteObservers", Params: []string{"tid", "muted"}, Tag: "tables", Summary: "mute the observers"},
+	{Method: "PUT", Path: "/tables/{tid}/owner", Stub: "TransferOwner", Params: []string{"tid", "uid"}, Tag: "tables", Summary: "transfer the table to a player"},
+
+	// tournaments
+	{Method: "POST", Path: "/tournaments/applications", Stub: "Apply", Results: []string{"host", "token"}, Tag: "tournaments", Summary: "apply for the tournament"},
+	{Method: "GET", Path: "/tournaments/tables", Stub: "GetTournamentHall", Params: []string{"pageSize", "page", "filterWait?"}, Results: []string{"hall"}, Tag: "tournaments", Summary: "a page of the tournament hall"},
+	{Method: "GET", Path: "/tournaments/tables/{tid}", Stub: "GetTournamentTable", Params: []string{"tid"}, Results: []string{"table"}, Tag: "tournaments", Summary: "a tournament table"},
+	{Method: "POST", Path: "/tournaments/tables/{tid}/observe", Stub: "ObserveTournament", Params: []string{"tid"}, Results: []string{"token"}, Tag: "tournaments", Summary: "get the token to observe a tournament table"},
+
+	// wallet
+	{Method: "POST", Path: "/wallet/withdrawals", Stub: "Withdraw", Params: []string{"amount", "address"}, Results: []string{"txid"}, Tag: "wallet", Summary: "withdraw mBTC to the address"},
+	{Method: "POST", Path: "/wallet/energy", Stub: "BuyEnergy", Params: []string{"amount"}, Tag: "wallet", Summary: "buy energy by mBTC"},
+}
+
+var restGateway *rest.Gateway
+
+// the routes call the same stubs, with the same session and rate limit checks of OnBeforeInvoke
+func initRestGateway() {
+	restGateway = rest.NewGateway(pubStub{}, restRoutes)
+	restGateway.Title, restGateway.Version, restGateway.Base = "go tetris", "1", restBase
+	restGateway.Before = func(fName string, params []reflect.Value, ctx interface{}) {
+		pubSe{}.OnBeforeInvoke(fName, params, false, ctx)
+	}
+}
+
+// the hprose rpc, and the rest gateway under restBase
+func pubHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", httpPubServer)
+	mux.Handle(restBase+"/", http.StripPrefix(restBase, restGateway))
+	return mux
+}
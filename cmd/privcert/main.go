@@ -0,0 +1,168 @@
+// generate the certificates of the private rpc between the auth server and the game servers
+// the first run creates a ca in the directory, the later runs sign with it
+//
+//	privcert -hosts 10.0.0.1 -name auth
+//	privcert -hosts 10.0.0.2 -name game1
+//
+// set tlsCert and tlsKey of a server to its certificate and key, and tlsCa of all the servers to ca.pem
+// keep ca.key offline
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	dir   = flag.String("dir", ".", "directory of the ca and the certificates")
+	hosts = flag.String("hosts", "", "comma separated ips or names of the server")
+	name  = flag.String("name", "server", "file name of the certificate and the key, without extension")
+	days  = flag.Int("days", 825, "days before the certificate expires")
+)
+
+const (
+	caCert = "ca.pem"
+	caKey  = "ca.key"
+)
+
+func main() {
+	flag.Parse()
+	if *hosts == "" {
+		fmt.Println("the hosts should be set")
+		os.Exit(1)
+	}
+	if err := run(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	ca, key, err := loadCa()
+	if os.IsNotExist(err) {
+		fmt.Println("create the ca in", *dir)
+		ca, key, err = createCa()
+	}
+	if err != nil {
+		return err
+	}
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	tmpl, err := template(*hosts)
+	if err != nil {
+		return err
+	}
+	tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	for _, h := range strings.Split(*hosts, ",") {
+		if h = strings.TrimSpace(h); net.ParseIP(h) != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, net.ParseIP(h))
+		} else if h != "" {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &priv.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	if err := writePem(*name+".pem", "CERTIFICATE", der, 0644); err != nil {
+		return err
+	}
+	fmt.Println("write", *name+".pem", "and", *name+".key")
+	return writeKey(*name+".key", priv)
+}
+
+func template(cn string) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"go_tetris private rpc"}, CommonName: cn},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(0, 0, *days),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, nil
+}
+
+func loadCa() (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	cb, err := readPem(caCert)
+	if err != nil {
+		return nil, nil, err
+	}
+	kb, err := readPem(caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	ca, err := x509.ParseCertificate(cb)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := x509.ParseECPrivateKey(kb)
+	return ca, key, err
+}
+
+func createCa() (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl, err := template("go_tetris private rpc ca")
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+	tmpl.KeyUsage |= x509.KeyUsageCertSign
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writePem(caCert, "CERTIFICATE", der, 0644); err != nil {
+		return nil, nil, err
+	}
+	if err := writeKey(caKey, key); err != nil {
+		return nil, nil, err
+	}
+	ca, err := x509.ParseCertificate(der)
+	return ca, key, err
+}
+
+func readPem(file string) ([]byte, error) {
+	b, err := ioutil.ReadFile(filepath.Join(*dir, file))
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("no pem block in %s", file)
+	}
+	return block.Bytes, nil
+}
+
+func writeKey(file string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return writePem(file, "EC PRIVATE KEY", der, 0600)
+}
+
+func writePem(file, typ string, der []byte, perm os.FileMode) error {
+	return ioutil.WriteFile(filepath.Join(*dir, file), pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), perm)
+}
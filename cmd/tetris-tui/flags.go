@@ -0,0 +1,12 @@
+package main
+
+import "flag"
+
+var (
+	hallUrl  = flag.String("hall", "http://rpc.cointetris.com/", "url of the auth hall public rpc server")
+	nickname = flag.String("user", "", "nickname to login with")
+	password = flag.String("pass", "", "password to login with")
+	pageSize = flag.Int("page", 9, "number of tables in a page of the hall")
+)
+
+func initFlags() { flag.Parse() }
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gogames/go_tetris/errcode"
+	"github.com/gogames/go_tetris/tetris"
+	"github.com/hprose/hprose-go/hprose"
+)
+
+// game server public rpc
+type gameStub struct {
+	Auth            func(string) (string, int, error)
+	SetLanguage     func(string, string) error
+	SwitchReady     func(string) error
+	SendChat        func(string, string) error
+	Operate         func(string, string) error
+	RequestKeyframe func(string) error
+	Quit            func(string) error
+	Ping            func(string) error
+	GetData         func(int, string) ([]string, int, error)
+}
+
+// response from game server
+type response struct {
+	Desc string          `json:"desc"`
+	Data json.RawMessage `json:"data"`
+}
+
+const pingInterval = 3 * time.Second
+
+// connect to the game server, play until quit
+func playGame(host, token string) error {
+	gClient := hprose.NewHttpClient(fmt.Sprintf("http://%s/", host))
+	gClient.SetKeepAlive(true)
+	game := new(gameStub)
+	gClient.UseService(&game)
+
+	sessionId, index, err := game.Auth(token)
+	if err != nil {
+		return fmt.Errorf("can not auth on game server %s: %v", host, err)
+	}
+	if err := game.SetLanguage(errcode.En, sessionId); err != nil {
+		return err
+	}
+
+	scr := newScreen()
+	done := make(chan bool)
+	defer close(done)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(pingInterval):
+				if err := game.Ping(sessionId); err != nil {
+					scr.sysMsg(fmt.Sprintf("ping error: %v", err))
+				}
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			vals, newIndex, err := game.GetData(index, sessionId)
+			if err != nil {
+				scr.sysMsg(fmt.Sprintf("can not get data: %v", err))
+				time.Sleep(time.Second)
+				continue
+			}
+			index = newIndex
+			for _, v := range vals {
+				if scr.handle(v) {
+					if err := game.RequestKeyframe(sessionId); err != nil {
+						scr.sysMsg(fmt.Sprintf("can not request keyframe: %v", err))
+					}
+				}
+			}
+			scr.draw()
+		}
+	}()
+
+	scr.draw()
+	return readKeys(func(key byte) bool {
+		var err error
+		switch key {
+		case 'a':
+			err = game.Operate("left", sessionId)
+		case 'd':
+			err = game.Operate("right", sessionId)
+		case 's':
+			err = game.Operate("down", sessionId)
+		case 'w':
+			err = game.Operate("rotate", sessionId)
+		case ' ':
+			err = game.Operate("drop", sessionId)
+		case 'h':
+			err = game.Operate("hold", sessionId)
+		case 'r':
+			err = game.SwitchReady(sessionId)
+		case 'k':
+			err = game.RequestKeyframe(sessionId)
+		case 't':
+			if msg := readChat(); msg != "" {
+				err = game.SendChat(msg, sessionId)
+			}
+			scr.draw()
+		case 'q':
+			if err := game.Quit(sessionId); err != nil {
+				scr.sysMsg(fmt.Sprintf("can not quit: %v", err))
+			}
+			return false
+		}
+		if err != nil {
+			scr.sysMsg(err.Error())
+			scr.draw()
+		}
+		return true
+	})
+}
+
+// decode the zone of a message from a player
+// return true if a keyframe is needed
+func (s *screen) handlePlayer(p int, data json.RawMessage) bool {
+	var msg map[string]json.RawMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		s.sysMsg(fmt.Sprintf("can not decode %s: %v", data, err))
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.boards[p]
+	for desc, val := range msg {
+		switch desc {
+		case tetris.DescZone:
+			var k tetris.ZoneKeyframe
+			if json.Unmarshal(val, &k) == nil {
+				b.zone, b.seq = k.Zone, k.Seq
+			}
+		case tetris.DescZoneDelta:
+			var d tetris.ZoneDelta
+			if json.Unmarshal(val, &d) != nil {
+				continue
+			}
+			// missing some deltas, wait for a keyframe
+			if b.zone == nil || d.Seq != b.seq+1 {
+				return true
+			}
+			d.Apply(b.zone)
+			b.seq = d.Seq
+		case tetris.DescHoldedPiece:
+			json.Unmarshal(val, &b.hold)
+		case tetris.DescNextPiece:
+			json.Unmarshal(val, &b.next)
+		case tetris.DescLines:
+			json.Unmarshal(val, &b.lines)
+		case tetris.DescKo:
+			json.Unmarshal(val, &b.ko)
+		case tetris.DescCombo:
+			json.Unmarshal(val, &b.combo)
+		}
+	}
+	return false
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gogames/go_tetris/errcode"
+	"github.com/hprose/hprose-go/hprose"
+)
+
+// auth hall public rpc
+type hallStub struct {
+	CreateSession      func() (string, error)
+	SetLanguage        func(string, string) error
+	Login              func(string, string, string) error
+	Logout             func(string) error
+	GetNormalHall      func(int, int, bool, string) ([]map[string]interface{}, error)
+	SearchTables       func(map[string]interface{}, string) ([]map[string]interface{}, string, error)
+	GetNormalTable     func(int, string) (map[string]interface{}, error)
+	CreateWithSettings func(string, int, map[string]int, string) (int, error)
+	Join               func(int, bool, string) (string, error)
+	JoinWithSecret     func(int, bool, string, string) (string, error)
+	AutoMatch          func(string) (string, string, error)
+}
+
+var (
+	hallClient hprose.Client
+	hall       = new(hallStub)
+	hallSessId = ""
+	stdin      = bufio.NewReader(os.Stdin)
+)
+
+func initHallClient() {
+	hallClient = hprose.NewHttpClient(*hallUrl)
+	hallClient.SetKeepAlive(true)
+	hallClient.UseService(&hall)
+}
+
+// read a line from stdin
+func readLine(prompt string) string {
+	fmt.Print(prompt)
+	line, _ := stdin.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+func login() (err error) {
+	if hallSessId, err = hall.CreateSession(); err != nil {
+		return err
+	}
+	if err = hall.SetLanguage(errcode.En, hallSessId); err != nil {
+		return err
+	}
+	name, pass := *nickname, *password
+	if name == "" {
+		name = readLine("nickname: ")
+	}
+	if pass == "" {
+		pass = readLine("password: ")
+	}
+	if err = hall.Login(name, pass, hallSessId); err != nil {
+		return err
+	}
+	*nickname = name
+	return nil
+}
+
+const hallHelp = `commands:
+	l             list tables
+	n, p          next, previous page
+	s [key=value ...]
+	              search the tables, the keys are sort (id, newest, bet, level), desc,
+	              waiting, bet_min, bet_max, level_min, level_max, obs_min, obs_max,
+	              free_seat, preset, title, owner, visibility (public, private)
+	m             more results of the search
+	j <tid> [secret], o <tid> [secret]
+	              join or observe a table, the secret is the password or the invite of a private table
+	c <title> <bet> [key=value ...]
+	              create a table and join it, the keys are
+	              height, width, next, interval (ms), duration (s), ko,
+	              series (first to n wins), settle (0 per game, 1 per series)
+	a             auto match
+	q             logout`
+
+// the hall menu, return the host and token of the game server to connect
+func hallMenu() (host, token string, ok bool) {
+	page := 1
+	tables := listTables(page)
+	var query map[string]interface{}
+	fmt.Println(hallHelp)
+	for {
+		fields := strings.Fields(readLine("hall> "))
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "l":
+			tables = listTables(page)
+		case "n":
+			page++
+			tables = listTables(page)
+		case "p":
+			if page > 1 {
+				page--
+			}
+			tables = listTables(page)
+		case "s":
+			query = make(map[string]interface{})
+			for _, f := range fields[1:] {
+				kv := strings.SplitN(f, "=", 2)
+				if len(kv) != 2 {
+					kv = append(kv, "true")
+				}
+				query[kv[0]] = kv[1]
+			}
+			tables = searchTables(query)
+		case "m":
+			if query == nil || query["cursor"] == "" {
+				fmt.Println("no more tables")
+				continue
+			}
+			tables = searchTables(query)
+		case "j", "o":
+			if len(fields) < 2 {
+				fmt.Println("which table?")
+				continue
+			}
+			tid, err := strconv.Atoi(fields[1])
+			if err != nil {
+				fmt.Println("incorrect table id:", fields[1])
+				continue
+			}
+			if host = tableHost(tid, tables); host == "" {
+				fmt.Println("can not find the table in the hall, list the tables first")
+				continue
+			}
+			secret := ""
+			if len(fields) > 2 {
+				secret = fields[2]
+			}
+			if token, err = hall.JoinWithSecret(tid, fields[0] == "o", secret, hallSessId); err != nil {
+				fmt.Println("can not join the table:", err)
+				continue
+			}
+			return host, token, true
+		case "c":
+			if len(fields) < 3 {
+				fmt.Println("c <title> <bet> [key=value ...]")
+				continue
+			}
+			bet, err := strconv.Atoi(fields[2])
+			if err != nil {
+				fmt.Println("incorrect bet:", fields[2])
+				continue
+			}
+			settings, err := parseSettings(fields[3:])
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			tid, err := hall.CreateWithSettings(fields[1], bet, settings, hallSessId)
+			if err != nil {
+				fmt.Println("can not create the table:", err)
+				continue
+			}
+			t, err := hall.GetNormalTable(tid, hallSessId)
+			if err != nil {
+				fmt.Println("can not get the table:", err)
+				continue
+			}
+			if token, err = hall.Join(tid, false, hallSessId); err != nil {
+				fmt.Println("can not join the table:", err)
+				continue
+			}
+			return fmt.Sprint(t["table_host"]), token, true
+		case "a":
+			host, token, err := hall.AutoMatch(hallSessId)
+			if err != nil {
+				fmt.Println("can not match an opponent:", err)
+				continue
+			}
+			return host, token, true
+		case "q":
+			if err := hall.Logout(hallSessId); err != nil {
+				fmt.Println("can not logout:", err)
+			}
+			return "", "", false
+		default:
+			fmt.Println(hallHelp)
+		}
+	}
+}
+
+func listTables(page int) []map[string]interface{} {
+	tables, err := hall.GetNormalHall(*pageSize, page, false, hallSessId)
+	if err != nil {
+		fmt.Println("can not get the hall:", err)
+		return nil
+	}
+	fmt.Printf("---- page %d ----\n", page)
+	printTables(tables)
+	return tables
+}
+
+// search the tables, the cursor of the next page is kept in the query
+func searchTables(query map[string]interface{}) []map[string]interface{} {
+	tables, cursor, err := hall.SearchTables(query, hallSessId)
+	if err != nil {
+		fmt.Println("can not search the hall:", err)
+		return nil
+	}
+	query["cursor"] = cursor
+	fmt.Println("---- search ----")
+	printTables(tables)
+	if cursor != "" {
+		fmt.Println("m for more")
+	}
+	return tables
+}
+
+func printTables(tables []map[string]interface{}) {
+	if len(tables) == 0 {
+		fmt.Println("no table")
+	}
+	for _, t := range tables {
+		private := ""
+		if t["table_private"] == true {
+			private = "private"
+		}
+		fmt.Printf("#%-4v %-16v bet %-4v %-6v %-7v %-20v 1p: %-10v 2p: %-10v obs: %v\n",
+			t["table_id"], t["table_title"], t["table_bet"], t["table_status"], private, settingsSummary(t["table_settings"]),
+			playerName(t["table_1p"]), playerName(t["table_2p"]), numOfObs(t["table_obs"]))
+	}
+}
+
+// e.g. 20x10 120s ko5, or 20x10 120s ko5 ft3 for a series
+func settingsSummary(v interface{}) string {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	s := fmt.Sprintf("%vx%v %vs ko%v", m["height"], m["width"], m["duration"], m["ko"])
+	if n := fmt.Sprint(m["series"]); n != "1" && n != "<nil>" {
+		s += " ft" + n
+	}
+	return s
+}
+
+// key=value pairs of the table settings
+func parseSettings(fields []string) (map[string]int, error) {
+	settings := make(map[string]int)
+	for _, f := range fields {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("incorrect setting %s, should be key=value", f)
+		}
+		v, err := strconv.Atoi(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("incorrect value of %s: %s", kv[0], kv[1])
+		}
+		settings[kv[0]] = v
+	}
+	return settings, nil
+}
+
+func tableHost(tid int, tables []map[string]interface{}) string {
+	for _, t := range tables {
+		if fmt.Sprint(t["table_id"]) == strconv.Itoa(tid) {
+			return fmt.Sprint(t["table_host"])
+		}
+	}
+	return ""
+}
+
+// nickname of the user in hall listing
+func playerName(u interface{}) string {
+	if m, ok := u.(map[string]interface{}); ok {
+		return fmt.Sprint(m["nickname"])
+	}
+	return "-"
+}
+
+func numOfObs(obs interface{}) int {
+	if l, ok := obs.([]interface{}); ok {
+		return len(l)
+	}
+	return 0
+}
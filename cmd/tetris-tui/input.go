@@ -0,0 +1,37 @@
+package main
+
+import "os/exec"
+
+// raw keyboard input, no echo, no line buffer
+func rawMode() {
+	exec.Command("stty", "-F", "/dev/tty", "cbreak", "min", "1").Run()
+	exec.Command("stty", "-F", "/dev/tty", "-echo").Run()
+}
+
+// back to line mode
+func lineMode() {
+	exec.Command("stty", "-F", "/dev/tty", "-cbreak").Run()
+	exec.Command("stty", "-F", "/dev/tty", "echo").Run()
+}
+
+// read keys until handle returns false
+func readKeys(handle func(key byte) bool) error {
+	rawMode()
+	defer lineMode()
+	for {
+		key, err := stdin.ReadByte()
+		if err != nil {
+			return err
+		}
+		if !handle(key) {
+			return nil
+		}
+	}
+}
+
+// read a chat message in line mode
+func readChat() string {
+	lineMode()
+	defer rawMode()
+	return readLine("chat: ")
+}
@@ -0,0 +1,33 @@
+// terminal client for the game server protocol
+// login through the auth hall, pick a table, then play or observe in the console
+//
+// keys in game:
+//
+//	a, d, s: move left, right, down
+//	w: rotate, space: drop, h: hold
+//	r: switch ready, t: chat, k: ask for zone keyframes, q: quit
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	initFlags()
+	initHallClient()
+
+	if err := login(); err != nil {
+		fmt.Println("can not login:", err)
+		os.Exit(1)
+	}
+	for {
+		host, token, ok := hallMenu()
+		if !ok {
+			return
+		}
+		if err := playGame(host, token); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
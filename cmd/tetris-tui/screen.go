@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gogames/go_tetris/tetris"
+)
+
+const maxChatLines = 6
+
+type piece [2][4]tetris.Color
+
+// what we know about a player
+type board struct {
+	zone  [][]tetris.Color
+	seq   int
+	hold  piece
+	next  []piece
+	lines int
+	ko    int
+	combo int
+}
+
+type screen struct {
+	mu     sync.Mutex
+	boards [2]*board
+	timer  int
+	chats  []string
+	sys    string
+}
+
+func newScreen() *screen {
+	return &screen{boards: [2]*board{new(board), new(board)}}
+}
+
+func (s *screen) sysMsg(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sys = msg
+}
+
+func (s *screen) chat(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chats = append(s.chats, msg)
+	if l := len(s.chats); l > maxChatLines {
+		s.chats = s.chats[l-maxChatLines:]
+	}
+}
+
+// handle a response from game server
+// return true if a keyframe is needed
+func (s *screen) handle(raw string) bool {
+	var r response
+	if err := json.Unmarshal([]byte(raw), &r); err != nil {
+		s.sysMsg(fmt.Sprintf("can not decode %s: %v", raw, err))
+		return false
+	}
+	var text string
+	switch r.Desc {
+	case "1p":
+		return s.handlePlayer(0, r.Data)
+	case "2p":
+		return s.handlePlayer(1, r.Data)
+	case "timer":
+		s.mu.Lock()
+		json.Unmarshal(r.Data, &s.timer)
+		s.mu.Unlock()
+	case "chat":
+		json.Unmarshal(r.Data, &text)
+		s.chat(text)
+	case "start":
+		var n int
+		json.Unmarshal(r.Data, &n)
+		if n > 0 {
+			s.sysMsg(fmt.Sprintf("game starts in %d", n))
+		} else {
+			s.sysMsg("game start!")
+		}
+	case "series":
+		var m map[string]interface{}
+		json.Unmarshal(r.Data, &m)
+		s.sysMsg(fmt.Sprintf("series %v:%v, first to %v", m["1p"], m["2p"], m["wins"]))
+	default:
+		// sysMsg, win, lose, result, error, refresh...
+		if json.Unmarshal(r.Data, &text) != nil {
+			text = string(r.Data)
+		}
+		s.sysMsg(fmt.Sprintf("%s: %s", r.Desc, text))
+	}
+	return false
+}
+
+func cell(c tetris.Color) string {
+	switch {
+	case c == 0:
+		return " ."
+	case c == -98:
+		return "()"
+	case c == -99:
+		return "##"
+	case c < 0:
+		return "[]"
+	}
+	// colorful blocks
+	return fmt.Sprintf("\033[4%dm  \033[0m", int(c)%7+1)
+}
+
+func pieceLines(p piece) []string {
+	lines := make([]string, 2)
+	for y := range p {
+		for _, c := range p[y] {
+			if c == 0 {
+				lines[y] += "  "
+			} else {
+				lines[y] += cell(c)
+			}
+		}
+	}
+	return lines
+}
+
+// the lines of a board with hold and next queue on the right
+func (b *board) render(title string) []string {
+	side := []string{"hold", ""}
+	side = append(side, pieceLines(b.hold)...)
+	side = append(side, "", "next")
+	for _, p := range b.next {
+		side = append(side, pieceLines(p)...)
+	}
+	side = append(side, "", fmt.Sprintf("lines %d", b.lines), fmt.Sprintf("ko    %d", b.ko), fmt.Sprintf("combo %d", b.combo))
+
+	width := 10
+	if len(b.zone) > 0 {
+		width = len(b.zone[0])
+	}
+	res := []string{fmt.Sprintf("%-*s", width*2+2, title)}
+	res = append(res, "+"+strings.Repeat("--", width)+"+")
+	for y, l := range b.zone {
+		str := "|"
+		for _, c := range l {
+			str += cell(c)
+		}
+		str += "|"
+		if y < len(side) {
+			str += " " + side[y]
+		}
+		res = append(res, str)
+	}
+	res = append(res, "+"+strings.Repeat("--", width)+"+")
+	return res
+}
+
+// visible width, without the escape codes
+func visible(s string) int {
+	n, esc := 0, false
+	for _, r := range s {
+		switch {
+		case r == '\033':
+			esc = true
+		case esc:
+			esc = r != 'm'
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+func (s *screen) draw() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l1 := s.boards[0].render("1P")
+	l2 := s.boards[1].render("2P")
+	out := "\033[H\033[2J"
+	out += fmt.Sprintf("time remaining: %d\r\n", s.timer)
+	for i := 0; i < len(l1) || i < len(l2); i++ {
+		var a, b string
+		if i < len(l1) {
+			a = l1[i]
+		}
+		if i < len(l2) {
+			b = l2[i]
+		}
+		out += a + strings.Repeat(" ", 40-visible(a)) + b + "\r\n"
+	}
+	out += "\r\n" + s.sys + "\r\n"
+	for _, c := range s.chats {
+		out += c + "\r\n"
+	}
+	out += "\r\n[a/d/s] move [w] rotate [space] drop [h] hold [r] ready [t] chat [k] keyframe [q] quit\r\n"
+	fmt.Print(out)
+}
@@ -0,0 +1,367 @@
+/*
+wire format of the game messages, shared by the game server and the clients
+
+the clients choose the format in Auth, json by default
+a binary message is a frame:
+
+	frame  = version desc sub value // version is a byte, see Version
+	desc   = string // the description of the response, e.g. "1p", "timer", "chat"
+	sub    = string // the description of the game message for "1p" and "2p", empty otherwise
+	string = uvarint(length) bytes
+	value  = tag body
+
+the tags of the values, the integers are zigzag varints:
+
+	0 nil
+	1 false
+	2 true
+	3 int     varint
+	4 float   8 bytes, IEEE 754 big endian
+	5 string  string
+	6 list    uvarint(n) value * n
+	7 map     uvarint(n) (string value) * n
+	8 zone    varint(seq) uvarint(rows) uvarint(cols) varint(color) * rows * cols
+	9 delta   varint(seq) uvarint(n) (uvarint(y) uvarint(x) varint(color)) * n
+
+the other values are encoded as their json, e.g. the pieces are lists of lists of colors
+a client should reject the frames of an unknown version
+*/
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/gogames/go_tetris/tetris"
+)
+
+// the version of the binary frames, the first byte
+const Version = 1
+
+// formats negotiated in Auth
+const (
+	FormatJSON   = "json"
+	FormatBinary = "binary"
+)
+
+var (
+	ErrFormat  = fmt.Errorf("unknown format")
+	ErrVersion = fmt.Errorf("unknown version of the binary frame")
+	ErrCorrupt = fmt.Errorf("the binary frame is corrupt")
+)
+
+// check the format from the client, empty for json
+func ParseFormat(format string) (string, error) {
+	switch format {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatBinary:
+		return FormatBinary, nil
+	}
+	return "", ErrFormat
+}
+
+const (
+	tagNil byte = iota
+	tagFalse
+	tagTrue
+	tagInt
+	tagFloat
+	tagString
+	tagList
+	tagMap
+	tagZone
+	tagDelta
+)
+
+// a decoded message
+// Data is one of nil, bool, int, float64, string, []interface{}, map[string]interface{},
+// tetris.ZoneKeyframe and tetris.ZoneDelta
+type Message struct {
+	Desc string
+	Sub  string
+	Data interface{}
+}
+
+// the game message of tetris, the description and the value
+type gameMessage interface {
+	Parts() (string, interface{})
+}
+
+// encode the response as a binary frame
+func Encode(desc string, data interface{}) ([]byte, error) {
+	var sub string
+	if m, ok := data.(gameMessage); ok {
+		sub, data = m.Parts()
+	}
+	e := &encoder{}
+	e.WriteByte(Version)
+	e.string(desc)
+	e.string(sub)
+	if err := e.value(data); err != nil {
+		return nil, err
+	}
+	return e.Bytes(), nil
+}
+
+// decode a binary frame
+func Decode(b []byte) (m Message, err error) {
+	d := &decoder{bytes.NewReader(b)}
+	defer func() {
+		// the reads panic on a short frame
+		if e := recover(); e != nil {
+			err = ErrCorrupt
+		}
+	}()
+	if v := d.byte(); v != Version {
+		return m, ErrVersion
+	}
+	m.Desc, m.Sub = d.string(), d.string()
+	m.Data = d.value()
+	if d.Len() != 0 {
+		return m, ErrCorrupt
+	}
+	return m, nil
+}
+
+type encoder struct{ bytes.Buffer }
+
+func (e *encoder) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	e.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (e *encoder) varint(v int64) {
+	var b [binary.MaxVarintLen64]byte
+	e.Write(b[:binary.PutVarint(b[:], v)])
+}
+
+func (e *encoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.WriteString(s)
+}
+
+func (e *encoder) value(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		e.WriteByte(tagNil)
+	case bool:
+		if v {
+			e.WriteByte(tagTrue)
+		} else {
+			e.WriteByte(tagFalse)
+		}
+	case int:
+		e.int(int64(v))
+	case int64:
+		e.int(v)
+	case float64:
+		e.WriteByte(tagFloat)
+		binary.Write(e, binary.BigEndian, math.Float64bits(v))
+	case string:
+		e.WriteByte(tagString)
+		e.string(v)
+	case []interface{}:
+		e.WriteByte(tagList)
+		e.uvarint(uint64(len(v)))
+		for _, val := range v {
+			if err := e.value(val); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		e.WriteByte(tagMap)
+		e.uvarint(uint64(len(v)))
+		for key, val := range v {
+			e.string(key)
+			if err := e.value(val); err != nil {
+				return err
+			}
+		}
+	case tetris.ZoneKeyframe:
+		e.WriteByte(tagZone)
+		e.varint(int64(v.Seq))
+		e.uvarint(uint64(len(v.Zone)))
+		cols := 0
+		if len(v.Zone) > 0 {
+			cols = len(v.Zone[0])
+		}
+		e.uvarint(uint64(cols))
+		for _, row := range v.Zone {
+			if len(row) != cols {
+				return fmt.Errorf("the zone is not a rectangle")
+			}
+			for _, c := range row {
+				e.varint(int64(c))
+			}
+		}
+	case tetris.ZoneDelta:
+		e.WriteByte(tagDelta)
+		e.varint(int64(v.Seq))
+		e.uvarint(uint64(len(v.Cells)))
+		for _, c := range v.Cells {
+			e.uvarint(uint64(c.Y))
+			e.uvarint(uint64(c.X))
+			e.varint(int64(c.Color))
+		}
+	default:
+		return e.other(v)
+	}
+	return nil
+}
+
+func (e *encoder) int(v int64) {
+	e.WriteByte(tagInt)
+	e.varint(v)
+}
+
+// the other integers, or the value as its json
+func (e *encoder) other(v interface{}) error {
+	if _, ok := v.(json.Marshaler); !ok {
+		switch rv := reflect.ValueOf(v); rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			e.int(rv.Int())
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+			e.int(int64(rv.Uint()))
+			return nil
+		}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return err
+	}
+	return e.value(fromJson(generic))
+}
+
+// the json numbers are integers if possible
+func fromJson(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = fromJson(v[i])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = fromJson(v[key])
+		}
+	}
+	return v
+}
+
+type decoder struct{ *bytes.Reader }
+
+func (d *decoder) byte() byte {
+	b, err := d.ReadByte()
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func (d *decoder) uvarint() uint64 {
+	v, err := binary.ReadUvarint(d)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	v, err := binary.ReadVarint(d)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// the length should not be longer than the rest of the frame
+func (d *decoder) length() int {
+	n := d.uvarint()
+	if n > uint64(d.Len()) {
+		panic(ErrCorrupt)
+	}
+	return int(n)
+}
+
+func (d *decoder) string() string {
+	b := make([]byte, d.length())
+	if _, err := d.Read(b); err != nil && len(b) > 0 {
+		panic(err)
+	}
+	return string(b)
+}
+
+func (d *decoder) value() interface{} {
+	switch tag := d.byte(); tag {
+	case tagNil:
+		return nil
+	case tagFalse:
+		return false
+	case tagTrue:
+		return true
+	case tagInt:
+		return int(d.varint())
+	case tagFloat:
+		var bits uint64
+		if err := binary.Read(d, binary.BigEndian, &bits); err != nil {
+			panic(err)
+		}
+		return math.Float64frombits(bits)
+	case tagString:
+		return d.string()
+	case tagList:
+		l := make([]interface{}, d.length())
+		for i := range l {
+			l[i] = d.value()
+		}
+		return l
+	case tagMap:
+		n := d.length()
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			key := d.string()
+			m[key] = d.value()
+		}
+		return m
+	case tagZone:
+		seq := int(d.varint())
+		rows, cols := d.length(), d.length()
+		if rows*cols > d.Len() {
+			panic(ErrCorrupt)
+		}
+		zone := make([][]tetris.Color, rows)
+		for y := range zone {
+			zone[y] = make([]tetris.Color, cols)
+			for x := range zone[y] {
+				zone[y][x] = tetris.Color(d.varint())
+			}
+		}
+		return tetris.ZoneKeyframe{Seq: seq, Zone: zone}
+	case tagDelta:
+		delta := tetris.ZoneDelta{Seq: int(d.varint())}
+		delta.Cells = make([]tetris.Cell, d.length())
+		for i := range delta.Cells {
+			delta.Cells[i] = tetris.Cell{Y: int(d.uvarint()), X: int(d.uvarint()), Color: tetris.Color(d.varint())}
+		}
+		return delta
+	default:
+		panic(ErrCorrupt)
+	}
+}
@@ -0,0 +1,72 @@
+package codec
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/gogames/go_tetris/tetris"
+)
+
+// like the pieces, encoded as their json
+type grid [2][2]tetris.Color
+
+func (g grid) MarshalJSON() ([]byte, error) { return json.Marshal([2][2]tetris.Color(g)) }
+
+func Test_Codec(t *testing.T) {
+	zone := tetris.ZoneKeyframe{Seq: 3, Zone: [][]tetris.Color{{0, 1, -99}, {-98, 7, 0}}}
+	delta := tetris.ZoneDelta{Seq: 4, Cells: []tetris.Cell{{Y: 1, X: 2, Color: -1}}}
+	cases := []struct {
+		desc string
+		data interface{}
+		want Message
+	}{
+		{"1p", tetris.NewMessage(tetris.DescZone, zone), Message{"1p", tetris.DescZone, zone}},
+		{"2p", tetris.NewMessage(tetris.DescZoneDelta, delta), Message{"2p", tetris.DescZoneDelta, delta}},
+		{"1p", tetris.NewMessage(tetris.DescAttack, 2), Message{"1p", tetris.DescAttack, 2}},
+		{"1p", tetris.NewMessage(tetris.DescNextPiece, grid{{1, 0}, {1, 1}}),
+			Message{"1p", tetris.DescNextPiece, []interface{}{[]interface{}{1, 0}, []interface{}{1, 1}}}},
+		{"timer", int64(-120), Message{"timer", "", -120}},
+		{"chat", "小明: hi", Message{"chat", "", "小明: hi"}},
+		{"series", map[string]interface{}{"over": true, "1p": 2, "rate": 0.5, "none": nil},
+			Message{"series", "", map[string]interface{}{"over": true, "1p": 2, "rate": 0.5, "none": nil}}},
+	}
+	for _, c := range cases {
+		b, err := Encode(c.desc, c.data)
+		if err != nil {
+			t.Fatalf("can not encode %v: %v", c.data, err)
+		}
+		m, err := Decode(b)
+		if err != nil {
+			t.Fatalf("can not decode %v: %v", c.data, err)
+		}
+		if !reflect.DeepEqual(m, c.want) {
+			t.Errorf("should decode %#v, got %#v", c.want, m)
+		}
+	}
+
+	// the zone is smaller than its json
+	b, _ := Encode("1p", tetris.NewMessage(tetris.DescZone, zone))
+	j, _ := json.Marshal(map[string]interface{}{"desc": "1p", "data": tetris.NewMessage(tetris.DescZone, zone)})
+	if len(b) >= len(j)/2 {
+		t.Errorf("the binary frame should be much smaller, %d bytes of %d", len(b), len(j))
+	}
+}
+
+func Test_CodecErrors(t *testing.T) {
+	b, _ := Encode("chat", "hello")
+	if _, err := Decode(append([]byte{Version + 1}, b[1:]...)); err != ErrVersion {
+		t.Errorf("should reject the unknown version, got %v", err)
+	}
+	for i := 0; i < len(b); i++ {
+		if _, err := Decode(b[:i]); err != ErrCorrupt && err != ErrVersion {
+			t.Errorf("should reject the short frame of %d bytes, got %v", i, err)
+		}
+	}
+	if _, err := Decode(append(b, 0)); err != ErrCorrupt {
+		t.Errorf("should reject the trailing bytes, got %v", err)
+	}
+	if _, err := ParseFormat("xml"); err != ErrFormat {
+		t.Errorf("should reject the unknown format, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package errcode
+
+var en = map[Code]string{
+	Unknown:            "%s",
+	CreateSessionFirst: "create a session first",
+	ServerClosing:      "the server is closing, the requests are not accepted at the moment",
+	TooFrequent:        "too many requests, retry after %s seconds",
+	Blacklisted:        "you are blacklisted for a while",
+	NotLoggedIn:        "log in first",
+	UnknownLanguage:    "the language %s is not supported",
+	IncorrectParam:     "the parameter %s is missing or not valid",
+	UnknownRoute:       "unknown api %s %s",
+
+	UserNotExist:            "the user %s does not exist",
+	IncorrectPwd:            "the password is incorrect",
+	IncorrectEmailFormat:    "the email is not valid",
+	IncorrectNicknameFormat: "the nickname should be 2 to 8 chinese characters, letters or digits",
+	IncorrectPasswordFormat: "the password should be 6 to 22 letters or digits",
+	EmailExist:              "the email is already taken",
+	NicknameExist:           "the nickname is already taken",
+	EncryptPassword:         "can not hash the password",
+	RegisterGetCodeFirst:    "send the verification code to your email before registering",
+	RegisterIncorrectCode:   "the verification code is incorrect, please check your email",
+	ForgetPassGetCodeFirst:  "send the verification code to your email before resetting the password",
+	ForgetPassIncorrectCode: "the verification code is incorrect, please check your email",
+	UnmatchedEmail:          "the verification code is sent to %s, register with that email",
+	ExceedMaxAvatar:         "the avatar should not be larger than 256KB, it is %sKB",
+	UnmatchNumOfFieldAndVal: "the numbers of the fields and the values do not match",
+	IncorrectType:           "the value of a field should be a string, an integer or []byte",
+
+	BalNotSufficient:   "the balance is not sufficient",
+	InvalidBtcAddr:     "the bitcoin address is not valid",
+	ExceedMinWithdraw:  "the minimum withdrawal is %s mBTC",
+	ExceedMinEnergy:    "buy at least %s mBTC of energy, 1 mBTC for %s energy",
+	InsufficientEnergy: "not enough energy, a game costs 1 energy",
+	NegativeBet:        "the bet should not be negative",
+
+	TableNotExist:       "the table %s does not exist, please join another one",
+	TableGameIsStarted:  "the game is started, please join another table",
+	TableIsFull:         "the table is full, please join another table",
+	AlreadyInGame:       "you are already in a game, quit it before joining another one",
+	CantMatchOpponent:   "no opponent is found, please try again later",
+	NoWorkingGameServer: "no game server is working at the moment",
+	Banned:              "you are banned from the table by its owner",
+	KickSelf:            "the owner can not kick themselves",
+	KickInGame:          "can not kick a player during the game or the series",
+	NotInTable:          "the user is not in the table",
+	ObserversMuted:      "the owner has muted the observers",
+	TransferToSelf:      "you are already the owner",
+	PrivateTable:        "the table is private, the password or the invitation is incorrect",
+	NotTableOwner:       "only the owner can change the table",
+	IncorrectCursor:     "the cursor is not valid, please search again",
+	UnknownQuery:        "unknown search condition %s",
+	IncorrectQuery:      "the value %[2]s of the search condition %[1]s is not valid",
+	UnknownSort:         "unknown sort %s",
+	UnknownPreset:       "unknown preset %s",
+	UnknownVisibility:   "unknown table type %s",
+	SettingOutOfRange:   "%s should be between %s and %s",
+	UnknownSetting:      "unknown setting %s",
+	RoomFull:            "the table is full",
+	TableNotFound:       "the table is not found",
+
+	NilTournament:             "there is no tournament to apply for",
+	NilTournamentHall:         "there is no tournament",
+	CantAcceptMoreApplication: "sorry, the tournament is full, please apply for the next one",
+
+	TokenError:         "the token is not valid",
+	TokenExpired:       "the token is expired, please enter again",
+	TokenAud:           "the token is not for this game server",
+	TokenUsed:          "the token is already used, please enter again",
+	ResumeToken:        "the resume token is not valid",
+	ResumeExpired:      "the session is expired, the game can not be resumed",
+	ResumeNotInTable:   "you are no longer in the table, the game can not be resumed",
+	AuthFirst:          "call Auth to create a session on the game server first",
+	GameServerInactive: "the game server is not serving at the moment",
+	UnsupportedFormat:  "unsupported format: %s",
+	ApplyFailed:        "can not apply for the tournament: %s",
+	JoinFailed:         "can not join the table: %s",
+	ObFailed:           "can not observe the game: %s",
+	UnknownOp:          "unknown op %s",
+}
@@ -0,0 +1,191 @@
+/*
+error codes of the rpc, shared by the servers and the clients
+
+an error is raised by panic in the rpc stubs as usual, the client gets its json:
+
+	{"code": 3001, "message": "余额不足", "args": ["..."]}
+
+the code is stable, match on it instead of the message
+the message is in the language of the session, see SetLanguage of the servers
+the args are the values in the message, e.g. the id of the table
+*/
+package errcode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type Code int
+
+// common
+const (
+	Unknown            Code = 1000 + iota // an error without a code, the text is the only arg
+	CreateSessionFirst                    // the session does not exist
+	ServerClosing
+	TooFrequent // the seconds to retry after
+	Blacklisted // no longer raised, the code is kept
+	NotLoggedIn
+	UnknownLanguage // the language
+	IncorrectParam  // the name of the param
+	UnknownRoute    // the method and the path
+)
+
+// account
+const (
+	UserNotExist Code = 2001 + iota // the uid, email or nickname
+	IncorrectPwd
+	IncorrectEmailFormat
+	IncorrectNicknameFormat
+	IncorrectPasswordFormat
+	EmailExist
+	NicknameExist
+	EncryptPassword
+	RegisterGetCodeFirst
+	RegisterIncorrectCode
+	ForgetPassGetCodeFirst
+	ForgetPassIncorrectCode
+	UnmatchedEmail  // the email the code is sent to
+	ExceedMaxAvatar // the size of the avatar in KB
+	UnmatchNumOfFieldAndVal
+	IncorrectType
+)
+
+// wallet
+const (
+	BalNotSufficient Code = 3001 + iota
+	InvalidBtcAddr
+	ExceedMinWithdraw // the min mBTC
+	ExceedMinEnergy   // the min mBTC, the energy of 1 mBTC
+	InsufficientEnergy
+	NegativeBet
+)
+
+// tables
+const (
+	TableNotExist Code = 4001 + iota // the table id
+	TableGameIsStarted
+	TableIsFull
+	AlreadyInGame
+	CantMatchOpponent
+	NoWorkingGameServer
+	Banned
+	KickSelf
+	KickInGame
+	NotInTable
+	ObserversMuted
+	TransferToSelf
+	PrivateTable
+	NotTableOwner
+	IncorrectCursor
+	UnknownQuery      // the key
+	IncorrectQuery    // the key, the value
+	UnknownSort       // the sort
+	UnknownPreset     // the preset
+	UnknownVisibility // the visibility
+	SettingOutOfRange // the setting, the min, the max
+	UnknownSetting    // the setting
+	RoomFull
+	TableNotFound
+)
+
+// tournament
+const (
+	NilTournament Code = 5001 + iota
+	NilTournamentHall
+	CantAcceptMoreApplication
+)
+
+// game server
+const (
+	TokenError Code = 6001 + iota
+	TokenExpired
+	TokenAud
+	TokenUsed
+	ResumeToken
+	ResumeExpired
+	ResumeNotInTable
+	AuthFirst
+	GameServerInactive
+	UnsupportedFormat // the error
+	ApplyFailed       // the error
+	JoinFailed        // the error
+	ObFailed          // the error
+	UnknownOp         // the op
+)
+
+// an error of the catalogue
+type Error struct {
+	Code Code
+	Args []string
+}
+
+// the args are formatted by fmt.Sprint
+func New(code Code, args ...interface{}) *Error {
+	e := &Error{Code: code, Args: make([]string, len(args))}
+	for i, arg := range args {
+		e.Args[i] = fmt.Sprint(arg)
+	}
+	return e
+}
+
+// the json in the default language, the servers localise it for the client
+func (e *Error) Error() string { return e.In(Default).String() }
+
+// the message in the language
+func (e *Error) Message(lang string) string {
+	format, ok := bundles[lang][e.Code]
+	if !ok {
+		format = bundles[Default][e.Code]
+	}
+	if format == "" {
+		return strings.Join(e.Args, ", ")
+	}
+	args := make([]interface{}, len(e.Args))
+	for i, arg := range e.Args {
+		args[i] = arg
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// the error to the client
+type Response struct {
+	Code    Code     `json:"code"`
+	Message string   `json:"message"`
+	Args    []string `json:"args,omitempty"`
+}
+
+func (e *Error) In(lang string) Response {
+	return Response{Code: e.Code, Message: e.Message(lang), Args: e.Args}
+}
+
+func (r Response) String() string {
+	b, _ := json.Marshal(r)
+	return string(b)
+}
+
+// parse the text of an error, e.g. from the other server
+// the text without a code is Unknown
+func Parse(text string) *Error {
+	var r Response
+	// the debug mode of hprose appends the stack
+	if strings.HasPrefix(text, "{") {
+		if err := json.NewDecoder(bytes.NewReader([]byte(text))).Decode(&r); err == nil && r.Code != 0 {
+			return &Error{Code: r.Code, Args: r.Args}
+		}
+	}
+	return &Error{Code: Unknown, Args: []string{text}}
+}
+
+// the text of an error in the language
+func Localize(text, lang string) Response { return Parse(text).In(lang) }
+
+// keep the code of err, or wrap it with code
+func Wrap(code Code, err error) *Error {
+	if e := Parse(err.Error()); e.Code != Unknown {
+		return e
+	}
+	return New(code, err)
+}
@@ -0,0 +1,79 @@
+package errcode
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+var verbReg = regexp.MustCompile(`%(\[\d\])?s`)
+
+func Test_Bundles(t *testing.T) {
+	for lang, bundle := range bundles {
+		for code, format := range zhCN {
+			other, ok := bundle[code]
+			if !ok {
+				t.Errorf("the message of %d is missing in %s", code, lang)
+				continue
+			}
+			if n, m := len(verbReg.FindAllString(format, -1)), len(verbReg.FindAllString(other, -1)); n != m {
+				t.Errorf("the message of %d should have %d args in %s, got %d", code, n, lang, m)
+			}
+		}
+		if len(bundle) != len(zhCN) {
+			t.Errorf("the bundle %s has %d messages, %s has %d", lang, len(bundle), ZhCN, len(zhCN))
+		}
+	}
+}
+
+func Test_Error(t *testing.T) {
+	e := New(TableNotExist, 12)
+	if msg := e.Message(En); msg != "the table 12 does not exist, please join another one" {
+		t.Errorf("unexpected message in en: %s", msg)
+	}
+	if msg := e.Message(ZhCN); msg != "桌子 12 不存在, 请加入别的桌子" {
+		t.Errorf("unexpected message in zh-CN: %s", msg)
+	}
+	if msg := e.Message("fr"); msg != e.Message(Default) {
+		t.Errorf("the unknown language should be the default, got %s", msg)
+	}
+	if msg := New(IncorrectQuery, "rows", "x").Message(En); msg != "the value x of the search condition rows is not valid" {
+		t.Errorf("unexpected message with indexed args: %s", msg)
+	}
+
+	// the text from another server, with the stack of the debug mode
+	r := Localize(e.Error()+"\r\ngoroutine 1 [running]", En)
+	if r.Code != TableNotExist || r.Message != e.Message(En) || len(r.Args) != 1 || r.Args[0] != "12" {
+		t.Errorf("the error should be parsed, got %+v", r)
+	}
+	if r := Localize("connection refused", En); r.Code != Unknown || r.Message != "connection refused" {
+		t.Errorf("the text without a code should be unknown, got %+v", r)
+	}
+
+	if w := Wrap(JoinFailed, e); w.Code != TableNotExist {
+		t.Errorf("the code should be kept, got %d", w.Code)
+	}
+	if w := Wrap(JoinFailed, fmt.Errorf("timeout")); w.Code != JoinFailed || w.Message(En) != "can not join the table: timeout" {
+		t.Errorf("the error should be wrapped, got %+v", w)
+	}
+}
+
+func Test_Language(t *testing.T) {
+	if l, err := ParseLanguage("EN"); err != nil || l != En {
+		t.Errorf("EN should be en, got %s, %v", l, err)
+	}
+	if _, err := ParseLanguage("fr"); err == nil {
+		t.Error("fr is not supported")
+	}
+	for header, lang := range map[string]string{
+		"":                     Default,
+		"en-US,en;q=0.8":       En,
+		"fr-FR, zh-TW;q=0.5":   ZhCN,
+		"fr-FR, de;q=0.5":      Default,
+		"zh-CN,zh;q=0.9,en;q=": ZhCN,
+	} {
+		if l := AcceptLanguage(header); l != lang {
+			t.Errorf("the language of %q should be %s, got %s", header, lang, l)
+		}
+	}
+}
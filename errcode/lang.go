@@ -0,0 +1,39 @@
+package errcode
+
+import "strings"
+
+// languages of the messages
+const (
+	ZhCN    = "zh-CN"
+	En      = "en"
+	Default = ZhCN
+)
+
+var bundles = map[string]map[Code]string{
+	ZhCN: zhCN,
+	En:   en,
+}
+
+// check the language set by the client, e.g. "en", "zh-CN"
+func ParseLanguage(lang string) (string, error) {
+	for l := range bundles {
+		if strings.EqualFold(l, lang) {
+			return l, nil
+		}
+	}
+	return "", New(UnknownLanguage, lang)
+}
+
+// the first supported language of Accept-Language, e.g. "en-US,en;q=0.8", default if none
+func AcceptLanguage(header string) string {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.ToLower(strings.TrimSpace(strings.SplitN(tag, ";", 2)[0]))
+		switch {
+		case tag == "zh" || strings.HasPrefix(tag, "zh-"):
+			return ZhCN
+		case tag == "en" || strings.HasPrefix(tag, "en-"):
+			return En
+		}
+	}
+	return Default
+}
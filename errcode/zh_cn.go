@@ -0,0 +1,81 @@
+package errcode
+
+var zhCN = map[Code]string{
+	Unknown:            "%s",
+	CreateSessionFirst: "请先创建session",
+	ServerClosing:      "服务器正在关闭, 暂不接受请求",
+	TooFrequent:        "请求过于频繁, 请 %s 秒后重试",
+	Blacklisted:        "正在黑名单中, 一会解禁",
+	NotLoggedIn:        "请先登陆",
+	UnknownLanguage:    "不支持的语言 %s",
+	IncorrectParam:     "参数 %s 缺失或不正确",
+	UnknownRoute:       "未知的接口 %s %s",
+
+	UserNotExist:            "用户 %s 不存在",
+	IncorrectPwd:            "密码错误",
+	IncorrectEmailFormat:    "邮箱格式错误",
+	IncorrectNicknameFormat: "昵称格式错误, 必须由2~8个中文,英文,数字组成",
+	IncorrectPasswordFormat: "密码格式错误, 必须由6~22个英文,数字组成",
+	EmailExist:              "邮箱已经被占用",
+	NicknameExist:           "昵称已经被占用",
+	EncryptPassword:         "密码哈希错误",
+	RegisterGetCodeFirst:    "需要先发送验证码到邮箱, 才能完成注册",
+	RegisterIncorrectCode:   "验证码错误, 请检查邮箱",
+	ForgetPassGetCodeFirst:  "需要先发送验证码到邮箱, 才能找回密码",
+	ForgetPassIncorrectCode: "验证码错误, 请检查邮箱",
+	UnmatchedEmail:          "验证码发到邮箱 %s, 注册邮箱也必须是这个! 请不要这样攻击我们的服务!",
+	ExceedMaxAvatar:         "头像大小不能超过256KB, 该头像大小为 %sKB.",
+	UnmatchNumOfFieldAndVal: "两个数组长度不一样",
+	IncorrectType:           "更新字段类型只能是字符串, 整形, 二进制流[]byte",
+
+	BalNotSufficient:   "余额不足",
+	InvalidBtcAddr:     "无效的比特币地址",
+	ExceedMinWithdraw:  "最低提现额度是 %smBTC",
+	ExceedMinEnergy:    "最低购买%smBTC能量, 每1mBTC可以充%s能量",
+	InsufficientEnergy: "能量不足, 每局游戏需消耗 1 能量",
+	NegativeBet:        "赌注不能为负数",
+
+	TableNotExist:       "桌子 %s 不存在, 请加入别的桌子",
+	TableGameIsStarted:  "游戏已经开始, 请加入别的桌子",
+	TableIsFull:         "桌子已满, 请加入别的桌子进行游戏",
+	AlreadyInGame:       "你已经在游戏中, 请先退出再加入另一个游戏",
+	CantMatchOpponent:   "无匹配对手, 请稍后重试.",
+	NoWorkingGameServer: "当前没有游戏服务器工作",
+	Banned:              "你已被桌主禁止进入这张桌子.",
+	KickSelf:            "桌主不能踢出自己.",
+	KickInGame:          "游戏或系列赛进行中, 不能踢出玩家.",
+	NotInTable:          "该用户不在桌子中.",
+	ObserversMuted:      "桌主已禁止观战者发言.",
+	TransferToSelf:      "不能把桌主转让给自己.",
+	PrivateTable:        "这是私人桌子, 密码或邀请码错误.",
+	NotTableOwner:       "只有桌主可以修改桌子.",
+	IncorrectCursor:     "分页游标错误, 请重新搜索.",
+	UnknownQuery:        "未知的搜索条件 %s",
+	IncorrectQuery:      "搜索条件 %s 的值 %s 不正确",
+	UnknownSort:         "未知的排序方式 %s",
+	UnknownPreset:       "未知的规则 %s",
+	UnknownVisibility:   "未知的桌子类型 %s",
+	SettingOutOfRange:   "%s必须在 %s 到 %s 之间",
+	UnknownSetting:      "未知的设置 %s",
+	RoomFull:            "桌子已满, 无法加入游戏.",
+	TableNotFound:       "找不到该桌子.",
+
+	NilTournament:             "暂无争霸赛, 无法加入.",
+	NilTournamentHall:         "暂无争霸赛, 无法获得争霸赛桌子信息",
+	CantAcceptMoreApplication: "不好意思, 报名人数已满, 请参加下期的争霸赛~",
+
+	TokenError:         "凭证不正确",
+	TokenExpired:       "凭证已过期, 请重新进入",
+	TokenAud:           "凭证不是这台游戏服务器的",
+	TokenUsed:          "凭证已经使用过, 请重新进入",
+	ResumeToken:        "恢复游戏的凭证不正确",
+	ResumeExpired:      "会话已过期, 无法恢复游戏",
+	ResumeNotInTable:   "你已经不在桌子里, 无法恢复游戏",
+	AuthFirst:          "先调用Auth 创建游戏服务器上的sessionId才能发送指令",
+	GameServerInactive: "游戏服务器暂时不服务",
+	UnsupportedFormat:  "不支持的数据格式, 错误: %s",
+	ApplyFailed:        "报名失败, 错误: %s",
+	JoinFailed:         "无法加入桌子, 错误: %s",
+	ObFailed:           "无法观战, 错误: %s",
+	UnknownOp:          "未知的指令 %s",
+}
@@ -4,8 +4,10 @@
 package main
 
 import (
+	"net"
+
+	"github.com/gogames/go_tetris/utils"
 	"github.com/hprose/hprose-go/hprose"
-	"github.com/xxtea/xxtea-go/xxtea"
 )
 
 type authServer struct {
@@ -18,28 +20,18 @@ type authServer struct {
 	Quit                func(tid, uid int, isTournament bool) error
 	SetNormalGameResult func(tid, winner, loser int) error
 	SetTournamentResult func(tid, winner, loser int) error
+	SetForfeitResult    func(tid, winner, loser int, reason string) error
 	Apply               func(uid int) (int, error)
 }
 
-type authFilter struct{}
-
-func (authFilter) InputFilter(data []byte, ctx interface{}) []byte {
-	return xxtea.Decrypt(data, privKey)
-}
-
-func (authFilter) OutputFilter(data []byte, ctx interface{}) []byte {
-	return xxtea.Encrypt(data, privKey)
-}
-
 var (
 	client         hprose.Client
 	authServerStub = new(authServer)
 )
 
 func initRpcClient() {
-	uri := "http://" + authServerIp + ":" + authServerRpcPort + "/"
-	client = hprose.NewHttpClient(uri)
+	filter := utils.NewPrivClientFilter(serverId, serverKey)
+	filter.Reject = func(err error) { log.Warn("reject the response of the auth server: %v", err) }
+	client = utils.NewPrivClient(net.JoinHostPort(authServerIp, authServerRpcPort), privTls, filter)
 	client.UseService(&authServerStub)
-	client.SetKeepAlive(true)
-	client.SetFilter(authFilter{})
 }
@@ -3,6 +3,9 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"sync"
+
+	"github.com/gogames/go_tetris/codec"
 )
 
 // response from game server to client
@@ -33,3 +36,39 @@ func (r responseData) toJson() string {
 	}
 	return string(b)
 }
+
+func (r responseData) toBinary() []byte {
+	b, err := codec.Encode(r.Desc, r.Data)
+	if err != nil {
+		log.Debug("can not encode the data %v: %v", r, err)
+	}
+	return b
+}
+
+// the response in the table datas, encoded once for each format when the clients read it
+type wireResponse struct {
+	responseData
+	jsonOnce, binaryOnce sync.Once
+	json                 string
+	binary               []byte
+}
+
+func (r responseData) wire() *wireResponse { return &wireResponse{responseData: r} }
+
+// a string of json or the bytes of a binary frame
+func (w *wireResponse) encode(format string) interface{} {
+	if format == codec.FormatBinary {
+		w.binaryOnce.Do(func() { w.binary = w.toBinary() })
+		return w.binary
+	}
+	w.jsonOnce.Do(func() { w.json = w.toJson() })
+	return w.json
+}
+
+// encode the responses from the table datas
+func encodeAll(res []interface{}, format string) []interface{} {
+	for i, r := range res {
+		res[i] = r.(*wireResponse).encode(format)
+	}
+	return res
+}
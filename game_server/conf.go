@@ -1,18 +1,22 @@
 package main
 
 import (
+	"crypto/tls"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/astaxie/beego/config"
 	"github.com/gogames/go_tetris/utils"
+	"github.com/gogames/go_tetris/utils/ratelimit"
 )
 
 var (
 	conf config.ConfigContainer
 
-	tokenEncryptKey      string
+	tokenKeys            string
+	tokenAudience        string // the host of this server in the tokens
 	logPath              string
 	authServerIp         string
 	authServerRpcPort    string
@@ -20,7 +24,12 @@ var (
 	gamePubServerRpcPort string
 	crossDomainFile      string
 	maxConn              int
-	privKey              []byte
+	serverId             string      // of this server in the gameServerKeys of the auth server
+	serverKey            []byte      // signs the private rpc
+	tlsCert, tlsKey      string      // of the rpc server, optional
+	privTls              *tls.Config // to the auth server, nil for plain http
+	rateLimits           map[string]ratelimit.Policy
+	rateLimitAllowlist   []string // ips or uid:N not limited
 )
 
 func initConf() {
@@ -31,14 +40,21 @@ func initConf() {
 		os.Exit(1)
 	}
 
-	tokenEncryptKey = conf.String("tokenEncryptKey")
+	tokenKeys = conf.String("tokenKeys")
+	tokenAudience = conf.String("tokenAudience")
 	logPath = conf.String("log")
 	authServerIp = conf.String("authServerIp")
 	authServerRpcPort = conf.String("authServerRpcPort")
 	gameServerRpcPort = conf.String("gameServerRpcPort")
 	gamePubServerRpcPort = conf.String("gamePubServerRpcPort")
 	crossDomainFile = conf.String("crossDomainFile")
-	privKeyString := conf.String("privKey")
+	serverId = conf.String("serverId")
+	serverKeyString := conf.String("serverKey")
+	tlsCert = conf.String("tlsCert")
+	tlsKey = conf.String("tlsKey")
+	tlsCa := conf.String("tlsCa")
+	rateLimitsString := conf.String("rateLimits")
+	rateLimitAllowlistString := conf.String("rateLimitAllowlist")
 	maxConn, err = conf.Int("maxConn")
 	if err != nil {
 		log.Critical("can not get maxConn: %v", err)
@@ -46,9 +62,32 @@ func initConf() {
 		os.Exit(1)
 	}
 
-	utils.CheckEmptyConf(tokenEncryptKey, logPath, authServerIp,
-		authServerRpcPort, gameServerRpcPort, gamePubServerRpcPort, maxConn)
+	utils.CheckEmptyConf(tokenKeys, tokenAudience, logPath, authServerIp,
+		authServerRpcPort, gameServerRpcPort, gamePubServerRpcPort, maxConn,
+		serverId, serverKeyString)
 
-	utils.SetTokenKey([]byte(tokenEncryptKey))
-	privKey = []byte(privKeyString)
+	if err := utils.SetTokenKeys(tokenKeys); err != nil {
+		log.Critical("can not parse token keys: %v", err)
+		time.Sleep(1 * time.Second)
+		os.Exit(1)
+	}
+	serverKey = []byte(serverKeyString)
+	if (tlsCert == "") != (tlsKey == "") {
+		log.Critical("tlsCert and tlsKey should be set together")
+		time.Sleep(1 * time.Second)
+		os.Exit(1)
+	}
+	if tlsCa != "" {
+		if privTls, err = utils.PrivTlsConfig(tlsCa); err != nil {
+			log.Critical("can not read tls ca: %v", err)
+			time.Sleep(1 * time.Second)
+			os.Exit(1)
+		}
+	}
+	if rateLimits, err = ratelimit.ParsePolicies(rateLimitsString); err != nil {
+		log.Critical("can not parse rate limits: %v", err)
+		time.Sleep(1 * time.Second)
+		os.Exit(1)
+	}
+	rateLimitAllowlist = strings.Split(rateLimitAllowlistString, ",")
 }
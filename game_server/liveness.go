@@ -0,0 +1,191 @@
+/*
+	liveness of the seated players
+	a player is disconnected if the client does not call the server for a while,
+	and forfeits if not reconnected in the grace period or idle for too long
+*/
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gogames/go_tetris/types"
+	"github.com/gogames/go_tetris/utils"
+	"github.com/gogames/go_tetris/utils/queue"
+)
+
+const (
+	livenessInterval = time.Second
+	disconnectAfter  = 10 // seconds without any call, the client keeps long polling GetData
+	reconnectGrace   = 30 // seconds to reconnect after being disconnected
+	afkWarnAfter     = 20 // seconds without any operation
+	afkLimit         = 40
+	// seconds without any call, the sessions not playing a game expire as soon as before
+	// only the players in game are kept for the reconnect grace, a bit longer than a long polling of GetData
+	waitingExpire = int64(getDataTimeout/time.Second) + 10
+)
+
+// reasons of forfeit, recorded by the auth server
+const (
+	forfeitDisconnect = "disconnect"
+	forfeitAfk        = "afk"
+)
+
+type livenessKey struct{ tid, uid int }
+
+// state of a seated player, to inform the table only once
+type livenessState struct {
+	disconnected bool
+	warned       bool
+}
+
+// only used by the liveness goroutine
+var livenessStates = make(map[livenessKey]*livenessState)
+
+func initLiveness() {
+	go checkLiveness()
+}
+
+func checkLiveness() {
+	defer utils.RecoverFromPanic("liveness check panic: ", log.Critical, checkLiveness)
+	for {
+		clock.Sleep(livenessInterval)
+		now := clock.Now().Unix()
+		checkTables(now)
+		expireSessions(now)
+	}
+}
+
+// the observers and the players waiting for a game expire after waitingExpire
+// deleting the sessions quits them, see gc
+func expireSessions(now int64) {
+	for sessId, vals := range session.GetAllSession() {
+		if ping, ok := vals[sessKeyPing].(int64); ok && now-ping > waitingExpire && !isPlaying(vals) {
+			session.DelSession(sessId)
+		}
+	}
+}
+
+// the session is of a player in game
+func isPlaying(vals map[string]interface{}) bool {
+	tid, _ := vals[sessKeyTid].(int)
+	uid, _ := vals[sessKeyUid].(int)
+	isOb, _ := vals[sessKeyIsOb].(bool)
+	table := tables.GetTableById(tid)
+	return !isOb && table != nil && table.IsStart() && (table.Get1pUid() == uid || table.Get2pUid() == uid)
+}
+
+func checkTables(now int64) {
+	seated := make(map[livenessKey]bool)
+	for _, table := range tables.GetAll() {
+		if !table.IsStart() {
+			continue
+		}
+		tid := table.GetTid()
+		for _, is1p := range []bool{true, false} {
+			uid := table.Get2pUid()
+			if is1p {
+				uid = table.Get1pUid()
+			}
+			key := livenessKey{tid, uid}
+			seated[key] = true
+			if livenessStates[key] == nil {
+				livenessStates[key] = new(livenessState)
+			}
+			if checkPlayer(table, key, is1p, now) {
+				break
+			}
+		}
+	}
+	for key := range livenessStates {
+		if !seated[key] {
+			delete(livenessStates, key)
+		}
+	}
+}
+
+// check a seated player, return true if the player forfeits
+func checkPlayer(table *types.Table, key livenessKey, is1p bool, now int64) bool {
+	st, u := livenessStates[key], table.GetUserById(key.uid)
+	if u == nil {
+		return false
+	}
+	lastPing, lastInput := int64(0), table.GetStartTime()
+	for _, sessId := range sessIdsOf(key.tid, key.uid) {
+		if ping, ok := session.GetSession(sessKeyPing, sessId).(int64); ok && ping > lastPing {
+			lastPing = ping
+		}
+		if input, ok := session.GetSession(sessKeyInput, sessId).(int64); ok && input > lastInput {
+			lastInput = input
+		}
+	}
+
+	// the sessions are deleted, handleQuit takes care of it
+	if lastPing == 0 {
+		return false
+	}
+
+	switch offline := now - lastPing; {
+	case offline >= disconnectAfter+reconnectGrace:
+		handleSysMsg(key.tid, fmt.Sprintf("玩家 %s 掉线超过 %d 秒, 判负", u.Nickname, reconnectGrace))
+		forfeit(key.tid, !is1p, forfeitDisconnect)
+		return true
+	case offline >= disconnectAfter:
+		if !st.disconnected {
+			st.disconnected = true
+			tableDatas.SetData(key.tid, newResponse(descDisconnected, map[string]interface{}{"uid": key.uid, "grace": reconnectGrace}).wire(), queue.BelongToAll)
+			handleSysMsg(key.tid, fmt.Sprintf("玩家 %s 掉线, %d 秒内未重新连接将判负", u.Nickname, reconnectGrace))
+		}
+		// the disconnected player is not checked for idle
+		return false
+	case st.disconnected:
+		st.disconnected = false
+		tableDatas.SetData(key.tid, newResponse(descReconnected, key.uid).wire(), queue.BelongToAll)
+		handleSysMsg(key.tid, fmt.Sprintf("玩家 %s 重新连接", u.Nickname))
+	}
+
+	belong := queue.BelongTo2p
+	if is1p {
+		belong = queue.BelongTo1p
+	}
+	switch idle := now - lastInput; {
+	case idle >= afkLimit:
+		handleSysMsg(key.tid, fmt.Sprintf("玩家 %s 超过 %d 秒没有操作, 判负", u.Nickname, afkLimit))
+		forfeit(key.tid, !is1p, forfeitAfk)
+		return true
+	case idle >= afkWarnAfter:
+		if !st.warned {
+			st.warned = true
+			tableDatas.SetData(key.tid, newResponse(descAfk, afkLimit-idle).wire(), belong)
+		}
+	default:
+		st.warned = false
+	}
+	return false
+}
+
+// the sessions of the user in the table
+func sessIdsOf(tid, uid int) []string {
+	sessIds := make([]string, 0)
+	for _, sessId := range session.SessIdsOf(sessKeyUid, uid) {
+		if session.GetSession(sessKeyTid, sessId) == tid {
+			sessIds = append(sessIds, sessId)
+		}
+	}
+	return sessIds
+}
+
+// stop the game, the opponent wins
+// inform the auth server to settle the bets and record the forfeit
+func forfeit(tid int, is1pWin bool, reason string) {
+	log.Info("table %d is forfeited: %s", tid, reason)
+	// the game may be over at the same time, see gameOver
+	table := tables.GetTableById(tid)
+	if table == nil || !table.StopGameIfStarted() {
+		return
+	}
+	winner, loser := winnerLoser(table, is1pWin)
+	if err := authServerStub.SetForfeitResult(tid, winner, loser, reason); err != nil {
+		log.Warn("can not set forfeit result for table %d: %v", tid, err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// the player stops calling the server, is disconnected, and forfeits after the grace
+func Test_DisconnectForfeit(t *testing.T) {
+	fc, ac := setupTest()
+	table := newTestTable(t, 101)
+	s1, s2 := newTestSession(t, 101, 1, false), newTestSession(t, 101, 2, false)
+	ob := newTestSession(t, 101, 3, true)
+	table.StartGame()
+	start := fc.Now().Unix()
+
+	// 2p keeps calling and operating, 1p does not
+	alive := func(now int64) {
+		session.SetSession(sessKeyPing, now, s2)
+		session.SetSession(sessKeyInput, now, s2)
+		session.SetSession(sessKeyPing, now, ob)
+	}
+	alive(start + disconnectAfter)
+	checkTables(start + disconnectAfter)
+	if descs := descsOf(ob); !hasDesc(descs, descDisconnected) {
+		t.Fatalf("the table should be informed of the disconnection, got %v", descs)
+	}
+	if !table.IsStart() {
+		t.Fatal("the player should not forfeit in the grace")
+	}
+
+	alive(start + disconnectAfter + reconnectGrace)
+	checkTables(start + disconnectAfter + reconnectGrace)
+	if table.IsStart() {
+		t.Fatal("the disconnected player should forfeit after the grace")
+	}
+	want := []string{"SetForfeitResult 101 2 1 disconnect"}
+	if calls := ac.get(); !reflect.DeepEqual(calls, want) {
+		t.Errorf("the auth server should settle the forfeit, got %v", calls)
+	}
+
+	// the game over of the stopped game does not settle it again
+	gameOver(101)
+	forfeit(101, true, forfeitAfk)
+	if calls := ac.get(); !reflect.DeepEqual(calls, want) {
+		t.Errorf("the game should be settled only once, got %v", calls)
+	}
+	if descs := descsOf(s1); len(descs) == 0 {
+		t.Error("the data of the disconnected player are kept for it")
+	}
+}
+
+// the player reconnects in the grace
+func Test_Reconnect(t *testing.T) {
+	fc, ac := setupTest()
+	table := newTestTable(t, 102)
+	s1, s2 := newTestSession(t, 102, 1, false), newTestSession(t, 102, 2, false)
+	table.StartGame()
+	defer table.StopGame()
+	start := fc.Now().Unix()
+
+	session.SetSession(sessKeyPing, start+disconnectAfter, s2)
+	checkTables(start + disconnectAfter)
+	for _, s := range []string{s1, s2} {
+		session.SetSession(sessKeyPing, start+disconnectAfter+1, s)
+		session.SetSession(sessKeyInput, start+disconnectAfter+1, s)
+	}
+	checkTables(start + disconnectAfter + 1)
+	if descs := descsOf(s2); !hasDesc(descs, descDisconnected) || !hasDesc(descs, descReconnected) {
+		t.Errorf("the table should be informed of the reconnection, got %v", descs)
+	}
+	checkTables(start + disconnectAfter + reconnectGrace)
+	if !table.IsStart() || len(ac.get()) != 0 {
+		t.Errorf("the reconnected player should not forfeit, got %v", ac.get())
+	}
+}
+
+// the players in game are kept for the grace, the others expire as before
+func Test_ExpireSessions(t *testing.T) {
+	fc, _ := setupTest()
+	table := newTestTable(t, 103)
+	s1, s2 := newTestSession(t, 103, 1, false), newTestSession(t, 103, 2, false)
+	ob := newTestSession(t, 103, 3, true)
+	newTestTable(t, 104)
+	waiting := newTestSession(t, 104, 4, false)
+	table.StartGame()
+	defer table.StopGame()
+
+	expireSessions(fc.Now().Unix() + waitingExpire + 1)
+	for _, s := range []string{s1, s2} {
+		if !session.IsSessIdExist(s) {
+			t.Error("the session of the player in game should be kept")
+		}
+	}
+	if session.IsSessIdExist(ob) || session.IsSessIdExist(waiting) {
+		t.Error("the sessions of the observer and the waiting player should expire")
+	}
+}
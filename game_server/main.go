@@ -1,20 +1,20 @@
 package main
 
-func init() {
+// the servers are started by main, so that the tests of the package do not start them
+func main() {
 	initFlags()
 	initConf()
 	initLogger()
 	initRpcClient()
 	initServerStatus()
+	initRateLimit()
 	initRpcServer()
 	initPubRpcServer()
 	initTables()
 	initTableDatas()
 	initSession()
+	initLiveness()
 	initGraceful()
-}
-
-func main() {
 	c := make(chan bool)
 	<-c
 }
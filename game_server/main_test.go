@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogames/go_tetris/timer"
+	"github.com/gogames/go_tetris/types"
+)
+
+// the calls of the game server to the auth server
+type authCalls struct {
+	calls []string
+	mu    sync.Mutex
+}
+
+func (ac *authCalls) add(format string, v ...interface{}) error {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.calls = append(ac.calls, fmt.Sprintf(format, v...))
+	return nil
+}
+
+func (ac *authCalls) get() []string {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	return append([]string(nil), ac.calls...)
+}
+
+// the game server runs on a fake clock without the sessions of the former test, and calls a fake auth server
+func setupTest() (*timer.FakeClock, *authCalls) {
+	fc := timer.NewFakeClock(time.Unix(1e9, 0))
+	clock = fc
+	livenessStates = make(map[livenessKey]*livenessState)
+	for sessionId := range session.GetAllSession() {
+		session.DelSession(sessionId)
+	}
+	ac := new(authCalls)
+	authServerStub = &authServer{
+		Join:        func(tid, uid int, isOb bool) error { return ac.add("Join %d %d %v", tid, uid, isOb) },
+		SwitchReady: func(tid, uid int) error { return ac.add("SwitchReady %d %d", tid, uid) },
+		Quit:        func(tid, uid int, isTournament bool) error { return ac.add("Quit %d %d", tid, uid) },
+		SetNormalGameResult: func(tid, winner, loser int) error {
+			return ac.add("SetNormalGameResult %d %d %d", tid, winner, loser)
+		},
+		SetForfeitResult: func(tid, winner, loser int, reason string) error {
+			return ac.add("SetForfeitResult %d %d %d %s", tid, winner, loser, reason)
+		},
+	}
+	return fc, ac
+}
+
+// a table created by the auth server, the table of the former test is deleted
+func newTestTable(t *testing.T, tid int) *types.Table {
+	tables.DelTable(tid)
+	tableDatas.DeleteTable(tid)
+	if err := (stub{}).Create(tid, nil); err != nil {
+		t.Fatal(err)
+	}
+	return tables.GetTableById(tid)
+}
+
+// the user joins the table, and gets a session as Auth does
+func newTestSession(t *testing.T, tid, uid int, isOb bool) string {
+	u := types.NewUser(uid, "", "", fmt.Sprintf("user%d", uid), "")
+	if err := tables.JoinTable(tid, u, isOb); err != nil {
+		t.Fatal(err)
+	}
+	sessionId := session.CreateSession()
+	session.SetSession(sessKeyUid, uid, sessionId)
+	session.SetSession(sessKeyNickname, u.Nickname, sessionId)
+	session.SetSession(sessKeyIsOb, isOb, sessionId)
+	session.SetSession(sessKeyIsTournament, false, sessionId)
+	session.SetSession(sessKeyTid, tid, sessionId)
+	session.SetSession(sessKeyIs1p, tables.GetTableById(tid).Is1p(uid), sessionId)
+	session.SetSession(sessKeyPing, clock.Now().Unix(), sessionId)
+	session.SetSession(sessKeyReader, sessionId, sessionId)
+	session.SetSession(sessKeyIndex, tableDatas.AddReader(tid, sessionId, belongOfSession(sessionId)), sessionId)
+	return sessionId
+}
+
+// the descriptions of the table datas the session has not got
+func descsOf(sessionId string) []string {
+	index, _ := session.GetSession(sessKeyIndex, sessionId).(int)
+	res, next, _ := tableDatas.GetData(getTidFromSession(sessionId), index, getReaderFromSession(sessionId), belongOfSession(sessionId))
+	session.SetSession(sessKeyIndex, next, sessionId)
+	descs := make([]string, 0, len(res))
+	for _, r := range res {
+		descs = append(descs, r.(*wireResponse).Desc)
+	}
+	return descs
+}
+
+func hasDesc(descs []string, desc string) bool {
+	for _, d := range descs {
+		if d == desc {
+			return true
+		}
+	}
+	return false
+}
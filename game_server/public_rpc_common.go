@@ -35,6 +35,14 @@ const (
 	descGameWin                    = "win"
 	descGameLose                   = "lose"
 	descGameResult                 = "result"
+	descKicked                     = "kicked"
+	descSeatLocked                 = "seatLocked"
+	descObsMuted                   = "obsMuted"
+	descOwner                      = "owner"
+	descDisconnected               = "disconnected"
+	descReconnected                = "reconnected"
+	descAfk                        = "afk"
+	descResync                     = "resync"
 )
 
 // quit a game
@@ -71,21 +79,21 @@ func handleQuit(tid, uid int, nickname string, isOb, is1p, isTournament bool) {
 	} else {
 		msg = fmt.Sprintf("玩家 %s 退出房间", nickname)
 	}
-	tableDatas.SetData(tid, newResponse(descSysMsg, msg).toJson(), queue.BelongToAll)
+	tableDatas.SetData(tid, newResponse(descSysMsg, msg).wire(), queue.BelongToAll)
 }
 
 // inform the client side to refresh the table information
 func handleRefresh(tid int, isTournament bool) {
 	if isTournament {
-		tableDatas.SetData(tid, newResponse(descRefreshTournamentTableInfo, tid).toJson(), queue.BelongToAll)
+		tableDatas.SetData(tid, newResponse(descRefreshTournamentTableInfo, tid).wire(), queue.BelongToAll)
 	} else {
-		tableDatas.SetData(tid, newResponse(descRefreshNormalTableInfo, tid).toJson(), queue.BelongToAll)
+		tableDatas.SetData(tid, newResponse(descRefreshNormalTableInfo, tid).wire(), queue.BelongToAll)
 	}
 }
 
 // send sys msg
 func handleSysMsg(tid int, msg string) {
-	tableDatas.SetData(tid, newResponse(descSysMsg, msg).toJson(), queue.BelongToAll)
+	tableDatas.SetData(tid, newResponse(descSysMsg, msg).wire(), queue.BelongToAll)
 }
 
 // send chat
@@ -95,10 +103,10 @@ func handleChat(tid int, isOb bool, msg string) {
 		return
 	}
 	if table.IsStart() && isOb {
-		tableDatas.SetData(tid, newResponse(descChatMsg, msg).toJson(), queue.BelongToObs)
+		tableDatas.SetData(tid, newResponse(descChatMsg, msg).wire(), queue.BelongToObs)
 		return
 	}
-	tableDatas.SetData(tid, newResponse(descChatMsg, msg).toJson(), queue.BelongToAll)
+	tableDatas.SetData(tid, newResponse(descChatMsg, msg).wire(), queue.BelongToAll)
 }
 
 // handle ready
@@ -155,6 +163,20 @@ func handleOperate(tid int, is1p bool, op string) {
 	}
 }
 
+// send zone keyframes of both players
+// for the clients who join late or miss some zone deltas
+func handleKeyframe(tid int) {
+	table := tables.GetTableById(tid)
+	if table == nil {
+		return
+	}
+	if !table.IsStart() {
+		return
+	}
+	table.GetGame1p().RequestKeyframe()
+	table.GetGame2p().RequestKeyframe()
+}
+
 // inform the auth server, some one is going to ob a game
 func obGame(tid, uid int, isTournament bool) error {
 	if isTournament {
@@ -8,14 +8,21 @@ import (
 	"net/http"
 	"reflect"
 
+	"github.com/gogames/go_tetris/errcode"
 	"github.com/gogames/go_tetris/utils"
 	"github.com/hprose/hprose-go/hprose"
 )
 
 var pubHttpServer = hprose.NewHttpService()
 
+// unix time of the last call, see checkLiveness
 const sessKeyPing = "ping"
 
+var (
+	errAuthFirst      = errcode.New(errcode.AuthFirst)
+	errServerInactive = errcode.New(errcode.GameServerInactive)
+)
+
 type pubStub struct{}
 
 type pubSe struct{}
@@ -24,24 +31,37 @@ var checkSessionId = func(params []reflect.Value) {
 	if l := len(params); l > 0 {
 		sessId := params[l-1].String()
 		if !session.IsSessIdExist(sessId) {
-			panic("先调用Auth 创建游戏服务器上的sessionId才能发送指令")
+			panic(errAuthFirst)
 		}
-		session.SetSession(sessKeyPing, 0, sessId)
+		session.SetSession(sessKeyPing, clock.Now().Unix(), sessId)
 	}
 }
 
 var panicOfServerStatus = func() {
 	if serverStatus != statusActive {
-		panic("游戏服务器暂时不服务")
+		panic(errServerInactive)
 	}
 }
 
 func (pubSe) OnBeforeInvoke(funcName string, params []reflect.Value, isSimple bool, ctx interface{}) {
 	log.Info(utils.HproseLog(funcName, params, ctx))
+	// the errors are in the language of the session
+	user := ""
+	if l := len(params); l > 0 {
+		if lang, ok := getLangFromSession(params[l-1].String()); ok {
+			utils.SetRequestLanguage(ctx, lang)
+		}
+		user = rateLimitUser(params[l-1].String())
+	}
+	utils.TakeToken(pubRateLimiter, funcName, user, ctx)
 
 	switch funcName {
-	case "Auth":
+	case "Auth", "AuthWithFormat":
 		panicOfServerStatus()
+	case "Resume":
+		panicOfServerStatus()
+	case "ResumeToken", "SetLanguage":
+		checkSessionId(params)
 	case "SwitchReady":
 		checkSessionId(params)
 		panicOfServerStatus()
@@ -50,6 +70,9 @@ func (pubSe) OnBeforeInvoke(funcName string, params []reflect.Value, isSimple bo
 		panicOfServerStatus()
 	case "Operate":
 		checkSessionId(params)
+	case "RequestKeyframe":
+		checkSessionId(params)
+		panicOfServerStatus()
 	case "Quit":
 		checkSessionId(params)
 	case "Ping":
@@ -73,12 +96,16 @@ func initPubRpcServer() {
 	pubHttpServer.AddMethods(pubStub{})
 	pubHttpServer.DebugEnabled = true
 	pubHttpServer.ServiceEvent = pubSe{}
+	pubHttpServer.SetFilter(utils.ErrorFilter{})
 	pubHttpServer.SetCrossDomainXmlFile(crossDomainFile)
 	go servePubHttp()
 }
 
 func servePubHttp() {
-	if err := http.ListenAndServe(fmt.Sprintf(":%s", gamePubServerRpcPort), pubHttpServer); err != nil {
+	mux := http.NewServeMux()
+	mux.Handle("/", pubHttpServer)
+	mux.Handle(wsPath, wsServer)
+	if err := http.ListenAndServe(fmt.Sprintf(":%s", gamePubServerRpcPort), mux); err != nil {
 		panic(err)
 	}
 }
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/gogames/go_tetris/codec"
+	"github.com/gogames/go_tetris/errcode"
 	"github.com/gogames/go_tetris/types"
 	"github.com/gogames/go_tetris/utils"
 	"github.com/gogames/go_tetris/utils/queue"
@@ -19,6 +21,11 @@ const (
 	sessKeyIsOb         = "isOb"
 	sessKeyIsTournament = "isTournament"
 	sessKeyIs1p         = "is1P"
+	sessKeyInput        = "input"  // unix time of the last operation
+	sessKeyIndex        = "index"  // the client has got the data before it, see Resume
+	sessKeyFormat       = "format" // codec.FormatJSON by default, see AuthWithFormat
+	sessKeyLang         = "lang"   // the language of the errors, see SetLanguage
+	sessKeyReader       = "reader" // the reader of the table datas, the first session id, kept by Resume
 )
 
 var (
@@ -28,21 +35,59 @@ var (
 	getIs1pFromSession         = func(sessionId string) bool { return session.GetSession(sessKeyIs1p, sessionId).(bool) }
 	getIsTournamentFromSession = func(sessionId string) bool { return session.GetSession(sessKeyIsTournament, sessionId).(bool) }
 	getNicknameFromSession     = func(sessionId string) string { return session.GetSession(sessKeyNickname, sessionId).(string) }
+	getFormatFromSession       = func(sessionId string) string {
+		if format, ok := session.GetSession(sessKeyFormat, sessionId).(string); ok {
+			return format
+		}
+		return codec.FormatJSON
+	}
+	getLangFromSession = func(sessionId string) (string, bool) {
+		lang, ok := session.GetSession(sessKeyLang, sessionId).(string)
+		return lang, ok
+	}
+	getReaderFromSession = func(sessionId string) string {
+		if reader, ok := session.GetSession(sessKeyReader, sessionId).(string); ok {
+			return reader
+		}
+		return sessionId
+	}
 )
 
+// the same as Auth, the data of the session are encoded in the format, json or binary
+func (pubStub) AuthWithFormat(token, format string) (sessionId string, index int) {
+	format, err := codec.ParseFormat(format)
+	if err != nil {
+		panic(errcode.New(errcode.UnsupportedFormat, err))
+	}
+	sessionId, index = pubStub{}.Auth(token)
+	session.SetSession(sessKeyFormat, format, sessionId)
+	return
+}
+
+// the nonces of the join tokens, a token is used only once
+var tokenNonces = utils.NewNonceStore()
+
+var errTokenUsed = errcode.New(errcode.TokenUsed)
+
 func (pubStub) Auth(token string) (sessionId string, index int) {
-	uid, nickname, isApply, isOb, isTournament, tid, err := utils.ParseToken(token)
+	tk, err := utils.ParseToken(token, tokenAudience)
 	if err != nil {
 		panic(err)
 	}
+	if !tokenNonces.Use(tk.Nonce, tk.Exp) {
+		panic(errTokenUsed)
+	}
+	uid, nickname, isApply, isOb, isTournament, tid := tk.Uid, tk.Nickname, tk.IsApply, tk.IsOb, tk.IsTournament, tk.Tid
 	u := types.NewUser(uid, "", "", nickname, "")
+	// the messages of joining are set after the session reads the table datas, so it gets them as well
+	var announce func()
 	switch {
 	case isApply:
 		// apply for tournament
 		tid, err := authServerStub.Apply(uid)
 		if err != nil {
 			log.Warn("can not apply for tournament, auth server error: %v", err)
-			panic(fmt.Sprintf("报名失败, 错误: %v", err))
+			panic(errcode.Wrap(errcode.ApplyFailed, err))
 		}
 		// if not exist, add new table
 		// if exist, do nothing
@@ -50,35 +95,39 @@ func (pubStub) Auth(token string) (sessionId string, index int) {
 		// the err should always be nil actually
 		if err := tables.JoinTable(tid, u, false); err != nil {
 			log.Debug("can not join the table, game server error: %v", err)
-			panic(fmt.Sprintf("无法加入桌子, 错误: %v", err))
+			panic(errcode.Wrap(errcode.JoinFailed, err))
+		}
+		announce = func() {
+			handleRefresh(tid, true)
+			handleSysMsg(tid, fmt.Sprintf("参赛者 %s 加入", nickname))
 		}
-		handleRefresh(tid, true)
-		handleSysMsg(tid, fmt.Sprintf("参赛者 %s 加入", nickname))
 	case isOb:
 		// inform the auth server that some one is going to observe a game
 		if err := obGame(tid, uid, isTournament); err != nil {
 			log.Warn("can not ob a game, auth server error: %v", err)
-			panic(fmt.Sprintf("无法观战, 错误: %v", err))
+			panic(errcode.Wrap(errcode.ObFailed, err))
 		}
 		if err := tables.JoinTable(tid, u, true); err != nil {
 			log.Critical("can not ob a game, game server error: %v", err)
-			panic(fmt.Sprintf("无法观战, 错误: %v", err))
+			panic(errcode.Wrap(errcode.ObFailed, err))
 		}
 		// do not inform all people that an observer join the table
 		// refreshTable(tid, isTournament)
-		handleSysMsg(tid, fmt.Sprintf("用户 %s 进入观战", nickname))
+		announce = func() { handleSysMsg(tid, fmt.Sprintf("用户 %s 进入观战", nickname)) }
 	default:
 		// normal hall
 		if err := authServerStub.Join(tid, uid, false); err != nil {
 			log.Warn("can not join a game, auth server error: %v", err)
-			panic(fmt.Sprintf("无法加入桌子, 错误: %v", err))
+			panic(errcode.Wrap(errcode.JoinFailed, err))
 		}
 		if err := tables.JoinTable(tid, u, isOb); err != nil {
 			log.Critical("can not join a game, game server error: %v", err)
-			panic(fmt.Sprintf("无法加入桌子, 错误: %v", err))
+			panic(errcode.Wrap(errcode.JoinFailed, err))
+		}
+		announce = func() {
+			handleRefresh(tid, false)
+			handleSysMsg(tid, fmt.Sprintf("玩家 %s 加入游戏", nickname))
 		}
-		handleRefresh(tid, false)
-		handleSysMsg(tid, fmt.Sprintf("玩家 %s 加入游戏", nickname))
 	}
 
 	sessionId = session.CreateSession()
@@ -88,11 +137,64 @@ func (pubStub) Auth(token string) (sessionId string, index int) {
 	session.SetSession(sessKeyIsTournament, isTournament, sessionId)
 	session.SetSession(sessKeyTid, tid, sessionId)
 	session.SetSession(sessKeyIs1p, tables.GetTableById(tid).Is1p(uid), sessionId)
+	session.SetSession(sessKeyPing, clock.Now().Unix(), sessionId)
+
+	session.SetSession(sessKeyReader, sessionId, sessionId)
+
+	index = tableDatas.AddReader(tid, sessionId, belongOfSession(sessionId))
+	session.SetSession(sessKeyIndex, index, sessionId)
+	announce()
+	// the game may be already started, the zone deltas are useless without a keyframe
+	handleKeyframe(tid)
+	return
+}
 
-	index = tableDatas.Index(tid)
+var (
+	errResumeExpired    = errcode.New(errcode.ResumeExpired)
+	errResumeNotInTable = errcode.New(errcode.ResumeNotInTable)
+)
+
+// the token to resume the session, the client keeps it in case of reloading
+func (pubStub) ResumeToken(sessionId string) string {
+	return utils.GenerateResumeToken(getUidFromSession(sessionId), getTidFromSession(sessionId), sessionId)
+}
+
+// a reloaded client takes its session back, the old session id is no longer valid
+// return the new session id, the index of the data the client has not got and a new resume token
+// the keyframes of the zones follow the index, so the client can draw the boards
+func (pubStub) Resume(resumeToken string) (sessionId string, index int, newToken string) {
+	uid, tid, oldId, err := utils.ParseResumeToken(resumeToken)
+	if err != nil {
+		panic(err)
+	}
+	// the session expires after the player forfeits or quits, see checkLiveness
+	if session.GetSession(sessKeyUid, oldId) != uid || session.GetSession(sessKeyTid, oldId) != tid {
+		panic(errResumeExpired)
+	}
+	table := tables.GetTableById(tid)
+	if table == nil || table.GetUserById(uid) == nil {
+		panic(errResumeNotInTable)
+	}
+	if sessionId = session.RebindSession(oldId); sessionId == "" {
+		panic(errResumeExpired)
+	}
+	log.Info("user %d resumes the session in table %d", uid, tid)
+	session.SetSession(sessKeyPing, clock.Now().Unix(), sessionId)
+	index, _ = session.GetSession(sessKeyIndex, sessionId).(int)
+	newToken = utils.GenerateResumeToken(uid, tid, sessionId)
+	handleKeyframe(tid)
 	return
 }
 
+// the language of the errors, "zh-CN" or "en", Accept-Language of the request by default
+func (pubStub) SetLanguage(lang string, sessionId string) {
+	lang, err := errcode.ParseLanguage(lang)
+	if err != nil {
+		panic(err)
+	}
+	session.SetSession(sessKeyLang, lang, sessionId)
+}
+
 // switch ready state
 func (pubStub) SwitchReady(sessionId string) {
 	handleReady(getTidFromSession(sessionId),
@@ -103,6 +205,11 @@ func (pubStub) SwitchReady(sessionId string) {
 
 // send chat info
 func (pubStub) SendChat(msg string, sessionId string) {
+	if getIsObFromSession(sessionId) {
+		if table := tables.GetTableById(getTidFromSession(sessionId)); table != nil && table.IsObsMuted() {
+			panic(types.ErrObserversMuted)
+		}
+	}
 	handleChat(getTidFromSession(sessionId),
 		getIsObFromSession(sessionId),
 		fmt.Sprintf("%s: %s", getNicknameFromSession(sessionId), msg))
@@ -111,12 +218,18 @@ func (pubStub) SendChat(msg string, sessionId string) {
 // operate game
 func (pubStub) Operate(op string, sessionId string) {
 	if !getIsObFromSession(sessionId) {
+		session.SetSession(sessKeyInput, clock.Now().Unix(), sessionId)
 		handleOperate(getTidFromSession(sessionId),
 			getIs1pFromSession(sessionId),
 			op)
 	}
 }
 
+// ask for zone keyframes, when the client misses some zone deltas
+func (pubStub) RequestKeyframe(sessionId string) {
+	handleKeyframe(getTidFromSession(sessionId))
+}
+
 // quit
 func (pubStub) Quit(sessionId string) {
 	handleQuit(getTidFromSession(sessionId),
@@ -132,25 +245,41 @@ func (pubStub) Quit(sessionId string) {
 // ping
 func (pubStub) Ping(sessionId string) {}
 
+// the data of the session belong to
+func belongOfSession(sessionId string) queue.DataBelong {
+	if getIsObFromSession(sessionId) {
+		return queue.BelongToObs
+	}
+	if getIs1pFromSession(sessionId) {
+		return queue.BelongTo1p
+	}
+	return queue.BelongTo2p
+}
+
+// long polling, at most getDataTimeout
+const getDataTimeout = 10 * time.Second
+
 // get data
 func (pubStub) GetData(index int, sessionId string) (res []interface{}, newIndex int) {
 	tid := getTidFromSession(sessionId)
-	var belong = queue.BelongToObs
-	if !getIsObFromSession(sessionId) {
-		if getIs1pFromSession(sessionId) {
-			belong = queue.BelongTo1p
-		} else {
-			belong = queue.BelongTo2p
-		}
-	}
-	var count = 200
+	session.SetSession(sessKeyIndex, index, sessionId)
+	reader, belong := getReaderFromSession(sessionId), belongOfSession(sessionId)
+	deadline := clock.Now().Add(getDataTimeout)
 	newIndex = index
-	for count > 0 {
-		count--
-		if res, newIndex = tableDatas.GetData(tid, newIndex, belong); res != nil {
-			return
+	for clock.Now().Before(deadline) {
+		var resync bool
+		if res, newIndex, resync = tableDatas.Wait(tid, newIndex, reader, belong, deadline.Sub(clock.Now())); resync {
+			res = resyncData(tid, newIndex)
+		}
+		if res != nil {
+			return encodeAll(res, getFormatFromSession(sessionId)), newIndex
 		}
-		time.Sleep(50 * time.Millisecond)
 	}
 	return
 }
+
+// the client is too far behind, it should reload the table, the keyframes follow
+func resyncData(tid, index int) []interface{} {
+	handleKeyframe(tid)
+	return []interface{}{newResponse(descResync, index).wire()}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gogames/go_tetris/utils/ratelimit"
+)
+
+const rateMetricsInterval = 10 * time.Minute
+
+// rate limits of the public rpc and the websocket, tokens a minute and the burst, by method
+// override them by rateLimits of the configuration
+var pubRateLimits = map[string]ratelimit.Policy{
+	ratelimit.Default: {Rate: 120, Burst: 30},
+	"GetData":         {Rate: 1200, Burst: 120}, // long polling
+	"Ping":            {Rate: 600, Burst: 60},
+	"Operate":         {Rate: 1200, Burst: 60},
+	"RequestKeyframe": {Rate: 60, Burst: 10},
+	"SendChat":        {Rate: 30, Burst: 5},
+	"Auth":            {Rate: 30, Burst: 10},
+	"AuthWithFormat":  {Rate: 30, Burst: 10},
+	"Resume":          {Rate: 30, Burst: 10},
+}
+
+var pubRateLimiter *ratelimit.Limiter
+
+func initRateLimit() {
+	pubRateLimiter = ratelimit.New(pubRateLimits)
+	pubRateLimiter.SetPolicies(rateLimits)
+	pubRateLimiter.Allow(rateLimitAllowlist...)
+	go logRateMetrics()
+}
+
+// the user of the session is limited by uid, otherwise by ip
+func rateLimitUser(sessionId string) string {
+	if uid, ok := session.GetSession(sessKeyUid, sessionId).(int); ok {
+		return fmt.Sprintf("uid:%d", uid)
+	}
+	return ""
+}
+
+// the rate limit metrics of the public rpc, the auth server collects them for the operator
+func (stub) RateLimitMetrics() map[string]interface{} { return pubRateLimiter.Stats() }
+
+func logRateMetrics() {
+	for {
+		time.Sleep(rateMetricsInterval)
+		metrics, buckets := pubRateLimiter.Metrics()
+		log.Info("rate limit of the public rpc, %d buckets: %v", buckets, metrics)
+	}
+}
@@ -5,7 +5,6 @@ package main
 
 import (
 	"fmt"
-	"net/http"
 	"reflect"
 
 	"github.com/gogames/go_tetris/utils"
@@ -25,7 +24,8 @@ func (se) OnBeforeInvoke(funcName string, params []reflect.Value, isSimple bool,
 
 	log.Info(utils.HproseLog(funcName, params, ctx))
 
-	if ip := utils.GetIp(ctx); ip != authServerIp {
+	// the request is signed by the key of this server
+	if ip := utils.GetIp(ctx); ip != authServerIp || utils.PrivIdOf(ctx) != serverId {
 		panic(fmt.Errorf("do not accept request from this client: %v", ip))
 	}
 }
@@ -40,11 +40,14 @@ func initRpcServer() {
 	httpServer.AddMethods(stub{})
 	httpServer.DebugEnabled = true
 	httpServer.ServiceEvent = se{}
+	filter := utils.NewPrivServerFilter(map[string][]byte{serverId: serverKey})
+	filter.Reject = func(err error) { log.Warn("reject the private rpc request: %v", err) }
+	httpServer.SetFilter(filter)
 	go serveHttp()
 }
 
 func serveHttp() {
-	if err := http.ListenAndServe(fmt.Sprintf(":%s", gameServerRpcPort), httpServer); err != nil {
+	if err := utils.ServePriv(fmt.Sprintf(":%s", gameServerRpcPort), tlsCert, tlsKey, httpServer); err != nil {
 		panic(err)
 	}
 }
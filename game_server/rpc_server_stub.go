@@ -21,16 +21,19 @@ func (stub) Deactivate() {
 	go deactivateServer(false)
 }
 
+// the clock of the count down, the games and the idle check of serveGame
+var clock timer.Clock = timer.RealClock
+
 // count down after a game start
 func countDown(tableId int) {
-	t := timer.NewTimer(1000)
+	t := timer.NewTimerWithClock(clock, 1000)
 	t.Start()
 	for i := 3; i > 0; i-- {
-		tableDatas.SetData(tableId, newResponse(descStart, i).toJson(), queue.BelongToAll)
+		tableDatas.SetData(tableId, newResponse(descStart, i).wire(), queue.BelongToAll)
 		t.Wait()
 	}
 	t.Stop()
-	tableDatas.SetData(tableId, newResponse(descStart, 0).toJson(), queue.BelongToAll)
+	tableDatas.SetData(tableId, newResponse(descStart, 0).wire(), queue.BelongToAll)
 }
 
 // auth server inform game server to start a table
@@ -60,11 +63,17 @@ func (stub) Start(tid int) {
 }
 
 // create new table
-func (stub) Create(tid int) error {
-	if err := tables.NewTable(tid, "", "", -1); err != nil {
+func (stub) Create(tid int, settings map[string]int) error {
+	ts, err := types.ParseTableSettings(settings)
+	if err != nil {
+		log.Debug("can not create new table with settings %v: %v", settings, err)
+		return err
+	}
+	if err := tables.NewTableWithSettings(tid, "", "", -1, ts); err != nil {
 		log.Debug("can not create new table: %v", err)
 		return err
 	}
+	tables.GetTableById(tid).SetClock(clock)
 	if err := tableDatas.NewTableData(tid); err != nil {
 		log.Debug("can not create new table data: %v", err)
 		return err
@@ -81,12 +90,85 @@ func (stub) Delete(tid int) error {
 		log.Critical("%v", err)
 		return err
 	}
-	tableDatas.SetData(tid, newResponse(descError, "桌子长时间不开始游戏, 或者由于其他原因, 桌子已经被取消.").toJson(), queue.BelongToAll)
+	tableDatas.SetData(tid, newResponse(descError, "桌子长时间不开始游戏, 或者由于其他原因, 桌子已经被取消.").wire(), queue.BelongToAll)
 	tables.DelTable(tid)
 	tableDatas.DeleteTable(tid)
 	return nil
 }
 
+// the kicked user is quit after a while, so that the client gets the message
+const kickDelay = 2 * time.Second
+
+// the owner kicks a user, the auth server has already checked the owner and banned the user
+func (stub) Kick(tid, uid int, ban bool) error {
+	table := tables.GetTableById(tid)
+	if table == nil {
+		return fmt.Errorf("can not kick the user %d because the table %d is not exist.", uid, tid)
+	}
+	u := table.GetUserById(uid)
+	if u == nil {
+		return fmt.Errorf("can not kick the user %d because the user is not in the table %d.", uid, tid)
+	}
+	tableDatas.SetData(tid, newResponse(descKicked, map[string]interface{}{"uid": uid, "ban": ban}).wire(), queue.BelongToAll)
+	if ban {
+		handleSysMsg(tid, fmt.Sprintf("%s 被桌主踢出, 并禁止再次进入", u.Nickname))
+	} else {
+		handleSysMsg(tid, fmt.Sprintf("%s 被桌主踢出", u.Nickname))
+	}
+	// deleting the sessions quits the user, see gc
+	sessIds := sessIdsOf(tid, uid)
+	clock.AfterFunc(kickDelay, func() {
+		for _, sessId := range sessIds {
+			session.DelSession(sessId)
+		}
+	})
+	return nil
+}
+
+// the owner locks or unlocks the second seat
+func (stub) LockSeat(tid int, locked bool) error {
+	table := tables.GetTableById(tid)
+	if table == nil {
+		return fmt.Errorf("can not lock the seat because the table %d is not exist.", tid)
+	}
+	tableDatas.SetData(tid, newResponse(descSeatLocked, locked).wire(), queue.BelongToAll)
+	if locked {
+		handleSysMsg(tid, "桌主锁定了座位")
+	} else {
+		handleSysMsg(tid, "桌主解锁了座位")
+	}
+	return nil
+}
+
+// the owner mutes or unmutes the observers
+func (stub) MuteObservers(tid int, muted bool) error {
+	table := tables.GetTableById(tid)
+	if table == nil {
+		return fmt.Errorf("can not mute the observers because the table %d is not exist.", tid)
+	}
+	table.SetObsMuted(muted)
+	tableDatas.SetData(tid, newResponse(descObsMuted, muted).wire(), queue.BelongToAll)
+	if muted {
+		handleSysMsg(tid, "桌主禁止了观战者发言")
+	} else {
+		handleSysMsg(tid, "桌主允许观战者发言")
+	}
+	return nil
+}
+
+// the owner hands the table to another user
+func (stub) TransferOwner(tid, uid int) error {
+	table := tables.GetTableById(tid)
+	if table == nil {
+		return fmt.Errorf("can not transfer the owner because the table %d is not exist.", tid)
+	}
+	tableDatas.SetData(tid, newResponse(descOwner, uid).wire(), queue.BelongToAll)
+	if u := table.GetUserById(uid); u != nil {
+		handleSysMsg(tid, fmt.Sprintf("%s 成为新的桌主", u.Nickname))
+	}
+	return nil
+}
+
 // auth server inform game server the game result
 func (stub) SetNormalGameResult(tid, winnerUid, bet int) {
 	construct := func(win bool, bet int) (str string) {
@@ -111,14 +193,14 @@ func (stub) SetNormalGameResult(tid, winnerUid, bet int) {
 	switch winnerUid {
 	case table.Get1pUid():
 		log.Debug("winner is 1p, sending win, lose, result msg via tcp")
-		tableDatas.SetData(tid, newResponse(descGameWin, construct(true, bet)).toJson(), queue.BelongTo1p)
-		tableDatas.SetData(tid, newResponse(descGameLose, construct(false, bet)).toJson(), queue.BelongTo2p)
-		tableDatas.SetData(tid, newResponse(descGameResult, "1P 赢得本局游戏").toJson(), queue.BelongToObs)
+		tableDatas.SetData(tid, newResponse(descGameWin, construct(true, bet)).wire(), queue.BelongTo1p)
+		tableDatas.SetData(tid, newResponse(descGameLose, construct(false, bet)).wire(), queue.BelongTo2p)
+		tableDatas.SetData(tid, newResponse(descGameResult, "1P 赢得本局游戏").wire(), queue.BelongToObs)
 	case table.Get2pUid():
 		log.Debug("winner is 2p, sending win, lose, result msg via tcp")
-		tableDatas.SetData(tid, newResponse(descGameWin, construct(true, bet)).toJson(), queue.BelongTo2p)
-		tableDatas.SetData(tid, newResponse(descGameLose, construct(false, bet)).toJson(), queue.BelongTo1p)
-		tableDatas.SetData(tid, newResponse(descGameResult, "2P 赢得本局游戏").toJson(), queue.BelongToObs)
+		tableDatas.SetData(tid, newResponse(descGameWin, construct(true, bet)).wire(), queue.BelongTo2p)
+		tableDatas.SetData(tid, newResponse(descGameLose, construct(false, bet)).wire(), queue.BelongTo1p)
+		tableDatas.SetData(tid, newResponse(descGameResult, "2P 赢得本局游戏").wire(), queue.BelongToObs)
 	default:
 		log.Debug("the winner uid is neither 1p nor 2p, who is it: %v", winnerUid)
 	}
@@ -126,6 +208,23 @@ func (stub) SetNormalGameResult(tid, winnerUid, bet int) {
 	// refreshTable(tid, false)
 }
 
+// auth server inform game server the score of the series
+func (stub) SetSeries(tid int, series map[string]interface{}) error {
+	if tables.GetTableById(tid) == nil {
+		return fmt.Errorf("can not set the series because the table %d is not exist.", tid)
+	}
+	tableDatas.SetData(tid, newResponse(types.DescSeries, series).wire(), queue.BelongToAll)
+	if rematch, _ := series["rematch"].(bool); !rematch {
+		return nil
+	}
+	if over, _ := series["over"].(bool); over {
+		handleSysMsg(tid, fmt.Sprintf("系列赛结束, 比分 %v:%v. 双方准备后开始新的系列赛", series["1p"], series["2p"]))
+	} else {
+		handleSysMsg(tid, fmt.Sprintf("比分 %v:%v, 先赢 %v 局者胜. 双方准备后开始下一局", series["1p"], series["2p"], series["wins"]))
+	}
+	return nil
+}
+
 // TODO: not confirmed yet
 // func (stub) SetTournamentResult(tid, winnerUid int, isFinalRound bool) {
 // 	construct := func(win, isFinalRound bool) (str string) {
@@ -149,8 +248,8 @@ func (stub) SetNormalGameResult(tid, winnerUid, bet int) {
 // 	}
 // 	switch winnerUid {
 // 	case table.Get1pUid():
-// 		tableDatas.SetData(tid, newResponse(descGameWin, construct(true, isFinalRound)).toJson(), queue.BelongTo1p)
-// 		tableDatas.SetData(tid, newResponse(descGameLose, construct(false, isFinalRound)).toJson(), queue.BelongTo2p)
+// 		tableDatas.SetData(tid, newResponse(descGameWin, construct(true, isFinalRound)).wire(), queue.BelongTo1p)
+// 		tableDatas.SetData(tid, newResponse(descGameLose, construct(false, isFinalRound)).wire(), queue.BelongTo2p)
 // 		closeConn(table.Get2pConn())
 // 		sendAll(descGameResult, "1P 赢得本局游戏", table.GetObConns()...)
 // 	case table.Get2pUid():
@@ -178,7 +277,7 @@ func serveGame(tid int) {
 		// table timer
 		case remain := <-table.RemainedSecondsChan:
 			log.Debug("remain time in seconds: %d", remain)
-			tableDatas.SetData(tid, newResponse(descTimer, remain).toJson(), queue.BelongToAll)
+			tableDatas.SetData(tid, newResponse(descTimer, remain).wire(), queue.BelongToAll)
 
 		// game over
 		case gameover := <-table.GameoverChan:
@@ -202,14 +301,17 @@ func serveGame(tid int) {
 			switch msg.Description {
 			// ko, audio only send to the player himself
 			case tetris.DescAudio, tetris.DescKo:
-				tableDatas.SetData(tid, newResponse(desc1p, msg).toJson(), queue.BelongTo1p)
+				tableDatas.SetData(tid, newResponse(desc1p, msg).wire(), queue.BelongTo1p)
 			// clear, combo, attack only sends to the player and obs
 			case tetris.DescClear, tetris.DescCombo, tetris.DescAttack:
-				tableDatas.SetData(tid, newResponse(desc1p, msg).toJson(), queue.BelongTo1p)
-				tableDatas.SetData(tid, newResponse(desc1p, msg).toJson(), queue.BelongToObs)
+				tableDatas.SetData(tid, newResponse(desc1p, msg).wire(), queue.BelongTo1p)
+				tableDatas.SetData(tid, newResponse(desc1p, msg).wire(), queue.BelongToObs)
+			// the zone keyframe supersedes the former zone frames
+			case tetris.DescZone, tetris.DescZoneDelta:
+				tableDatas.SetFrame(tid, desc1p, msg.Description == tetris.DescZone, newResponse(desc1p, msg).wire(), queue.BelongToAll)
 			// the others send to all
 			default:
-				tableDatas.SetData(tid, newResponse(desc1p, msg).toJson(), queue.BelongToAll)
+				tableDatas.SetData(tid, newResponse(desc1p, msg).wire(), queue.BelongToAll)
 			}
 
 		case beingKo := <-table.GetGame1p().BeingKOChan:
@@ -217,10 +319,10 @@ func serveGame(tid int) {
 			if beingKo {
 				table.GetGame2p().KoOpponent()
 				ko := table.GetGame2p().GetKo()
-				tableDatas.SetData(tid, newResponse(desc1p, tetris.NewMessage(tetris.DescBeingKo, ko)).toJson(), queue.BelongTo1p)
-				tableDatas.SetData(tid, newResponse(desc1p, tetris.NewMessage(tetris.DescBeingKo, ko)).toJson(), queue.BelongToObs)
+				tableDatas.SetData(tid, newResponse(desc1p, tetris.NewMessage(tetris.DescBeingKo, ko)).wire(), queue.BelongTo1p)
+				tableDatas.SetData(tid, newResponse(desc1p, tetris.NewMessage(tetris.DescBeingKo, ko)).wire(), queue.BelongToObs)
 				log.Debug("number of 2p ko: %d", ko)
-				if ko >= 5 {
+				if ko >= table.GetSettings().KOLimit {
 					log.Debug("send true to 1p gameover chan")
 					table.GetGame1p().GameoverChan <- true
 				}
@@ -245,12 +347,14 @@ func serveGame(tid int) {
 			// ko, audio only send to the player himself
 			switch msg.Description {
 			case tetris.DescAudio, tetris.DescKo:
-				tableDatas.SetData(tid, newResponse(desc2p, msg).toJson(), queue.BelongTo2p)
+				tableDatas.SetData(tid, newResponse(desc2p, msg).wire(), queue.BelongTo2p)
 			case tetris.DescClear, tetris.DescCombo, tetris.DescAttack:
-				tableDatas.SetData(tid, newResponse(desc2p, msg).toJson(), queue.BelongTo2p)
-				tableDatas.SetData(tid, newResponse(desc2p, msg).toJson(), queue.BelongToObs)
+				tableDatas.SetData(tid, newResponse(desc2p, msg).wire(), queue.BelongTo2p)
+				tableDatas.SetData(tid, newResponse(desc2p, msg).wire(), queue.BelongToObs)
+			case tetris.DescZone, tetris.DescZoneDelta:
+				tableDatas.SetFrame(tid, desc2p, msg.Description == tetris.DescZone, newResponse(desc2p, msg).wire(), queue.BelongToAll)
 			default:
-				tableDatas.SetData(tid, newResponse(desc2p, msg).toJson(), queue.BelongToAll)
+				tableDatas.SetData(tid, newResponse(desc2p, msg).wire(), queue.BelongToAll)
 			}
 
 		// attack 1p
@@ -271,20 +375,23 @@ func serveGame(tid int) {
 			if beingKo {
 				table.GetGame1p().KoOpponent()
 				ko := table.GetGame1p().GetKo()
-				tableDatas.SetData(tid, newResponse(desc2p, tetris.NewMessage(tetris.DescBeingKo, ko)).toJson(),
+				tableDatas.SetData(tid, newResponse(desc2p, tetris.NewMessage(tetris.DescBeingKo, ko)).wire(),
 					queue.BelongTo2p)
-				tableDatas.SetData(tid, newResponse(desc2p, tetris.NewMessage(tetris.DescBeingKo, ko)).toJson(),
+				tableDatas.SetData(tid, newResponse(desc2p, tetris.NewMessage(tetris.DescBeingKo, ko)).wire(),
 					queue.BelongToObs)
 				log.Debug("number of 1p ko: %d", ko)
-				if ko >= 5 {
+				if ko >= table.GetSettings().KOLimit {
 					log.Debug("send true to 2p gameover chan")
 					table.GetGame2p().GameoverChan <- true
 				}
 			}
 
-		case <-time.After(time.Second * 2):
-			log.Debug("do not receive any msg in 2 seconds: the game should be ended")
-			return
+		case <-clock.After(time.Second * 2):
+			// the players may be disconnected, see checkLiveness
+			if !table.IsStart() {
+				log.Debug("do not receive any msg in 2 seconds: the game is ended")
+				return
+			}
 		}
 	}
 }
@@ -298,13 +405,12 @@ func gameOver(tid int, is1pWin ...bool) {
 		log.Critical("game over but the table is nil")
 		return
 	}
-	if !table.IsStart() {
-		log.Critical("the game is actually not start, why game over?")
+	// the player may forfeit at the same time, see forfeit
+	if !table.StopGameIfStarted() {
+		log.Warn("the game of table %d is not started or already forfeited, why game over?", tid)
 		return
 	}
-	table.StopGame()
 	var is1pWinner = false
-	var winner, loser int
 	var err error
 
 	// normal checker
@@ -324,11 +430,7 @@ func gameOver(tid int, is1pWin ...bool) {
 	}
 
 	// inform the auth server
-	if is1pWinner {
-		winner, loser = table.Get1pUid(), table.Get2pUid()
-	} else {
-		winner, loser = table.Get2pUid(), table.Get1pUid()
-	}
+	winner, loser := winnerLoser(table, is1pWinner)
 
 	// 1e5 magic number
 	if tid >= 1e5 {
@@ -340,3 +442,10 @@ func gameOver(tid int, is1pWin ...bool) {
 		log.Warn("can not set game result for table %d: %v", tid, err)
 	}
 }
+
+func winnerLoser(table *types.Table, is1pWin bool) (winner, loser int) {
+	if is1pWin {
+		return table.Get1pUid(), table.Get2pUid()
+	}
+	return table.Get2pUid(), table.Get1pUid()
+}
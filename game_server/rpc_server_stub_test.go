@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+// the table is informed of the kick at once, the sessions of the kicked user are deleted after the delay
+func Test_Kick(t *testing.T) {
+	fc, _ := setupTest()
+	newTestTable(t, 105)
+	s1, s2 := newTestSession(t, 105, 1, false), newTestSession(t, 105, 2, false)
+	ob := newTestSession(t, 105, 3, true)
+
+	if err := (stub{}).Kick(105, 2, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, sessId := range []string{s1, s2, ob} {
+		if descs := descsOf(sessId); !hasDesc(descs, descKicked) || !hasDesc(descs, descSysMsg) {
+			t.Errorf("the session %s should be informed of the kick, got %v", sessId, descs)
+		}
+	}
+	if !session.IsSessIdExist(s2) {
+		t.Fatal("the kicked user should get the notification before the session is deleted")
+	}
+
+	fc.Advance(kickDelay)
+	if session.IsSessIdExist(s2) {
+		t.Error("the session of the kicked user should be deleted after the delay")
+	}
+	if !session.IsSessIdExist(s1) || !session.IsSessIdExist(ob) {
+		t.Error("the sessions of the others should be kept")
+	}
+
+	if err := (stub{}).Kick(105, 4, false); err == nil {
+		t.Error("the user not in the table can not be kicked")
+	}
+}
@@ -2,7 +2,9 @@ package main
 
 import "github.com/gogames/go_tetris/utils"
 
-var session = utils.NewSessionStore(10)
+// longer than the reconnect grace, the disconnected players forfeit before their sessions expire
+// the others expire after waitingExpire, see expireSessions
+var session = utils.NewSessionStore(disconnectAfter + reconnectGrace + 20)
 
 const garbageBuffer = 1 << 10
 
@@ -14,6 +16,9 @@ func initSession() {
 func gc() {
 	for {
 		sess := <-session.GarbageSession
+		if reader, ok := sess.Get(sessKeyReader).(string); ok {
+			tableDatas.RemoveReader(sess.Get(sessKeyTid).(int), reader)
+		}
 		handleQuit(sess.Get(sessKeyTid).(int),
 			sess.Get(sessKeyUid).(int),
 			sess.Get(sessKeyNickname).(string),
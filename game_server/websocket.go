@@ -0,0 +1,236 @@
+/*
+	websocket transport, beside the hprose api for the old clients
+	the data of the table are pushed as they arrive, and the client sends the inputs upstream
+*/
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"code.google.com/p/go.net/websocket"
+	"github.com/gogames/go_tetris/codec"
+	"github.com/gogames/go_tetris/errcode"
+	"github.com/gogames/go_tetris/utils"
+	"github.com/gogames/go_tetris/utils/ratelimit"
+	"github.com/hprose/hprose-go/hprose"
+)
+
+const (
+	wsPath         = "/ws"
+	wsPingInterval = 5 * time.Second
+	wsReadTimeout  = 3 * wsPingInterval // the client answers the ping with pong
+)
+
+// ops of the frames from the client
+const (
+	wsOpAuth     = "auth"   // token from the auth server and the format in data, the first frame
+	wsOpResume   = "resume" // resume token, the first frame, the format of the session is kept
+	wsOpOperate  = "operate"
+	wsOpChat     = "chat"
+	wsOpReady    = "ready"
+	wsOpKeyframe = "keyframe"
+	wsOpLang     = "lang" // the language of the errors in data
+	wsOpPong     = "pong"
+	wsOpQuit     = "quit"
+)
+
+// frame from the client
+type wsFrame struct {
+	Op    string `json:"op"`
+	Token string `json:"token,omitempty"`
+	Data  string `json:"data,omitempty"`
+}
+
+// the responses are sent by the reader and the pusher
+type wsConn struct {
+	*websocket.Conn
+	format string
+	lang   string // of Accept-Language, unless set in the session
+	ip     string // rate limited by ip before auth, see pubRateLimits
+	mu     sync.Mutex
+}
+
+// a text frame of json or a binary frame
+func (c *wsConn) send(resp responseData) error {
+	return c.sendEncoded(resp.wire().encode(c.format))
+}
+
+// the error with its code, in the language of the session
+func (c *wsConn) sendError(err error, sessionId string) error {
+	lang, ok := getLangFromSession(sessionId)
+	if !ok {
+		lang = c.lang
+	}
+	return c.send(newResponse(descError, errcode.Localize(err.Error(), lang)))
+}
+
+func (c *wsConn) sendEncoded(data interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return websocket.Message.Send(c.Conn, data)
+}
+
+// the websocket does not check the origin, the same as the cross domain file of hprose
+var wsServer = websocket.Server{Handler: serveWs}
+
+func serveWs(ws *websocket.Conn) {
+	c := &wsConn{Conn: ws, format: codec.FormatJSON}
+	c.lang = errcode.AcceptLanguage(ws.Request().Header.Get("Accept-Language"))
+	c.ip = utils.GetIp(&hprose.HttpContext{Request: ws.Request()})
+	defer c.Close()
+	sessionId, index, err := wsAuth(c)
+	if err != nil {
+		log.Debug("websocket auth failed: %v", err)
+		c.sendError(err, sessionId)
+		return
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go wsPush(c, sessionId, index, done)
+
+	for {
+		var f wsFrame
+		c.SetReadDeadline(time.Now().Add(wsReadTimeout))
+		if err := websocket.JSON.Receive(c.Conn, &f); err != nil {
+			// the session is kept, the client may reconnect or resume, see checkLiveness
+			log.Debug("websocket of session %s is closed: %v", sessionId, err)
+			return
+		}
+		if !session.IsSessIdExist(sessionId) {
+			return
+		}
+		session.SetSession(sessKeyPing, clock.Now().Unix(), sessionId)
+		if err := wsInvoke(f, c.ip, sessionId); err != nil {
+			c.sendError(err, sessionId)
+		}
+		if f.Op == wsOpQuit {
+			return
+		}
+	}
+}
+
+// the same as Auth or Resume of hprose
+func wsAuth(c *wsConn) (sessionId string, index int, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("%v", e)
+		}
+	}()
+	var f wsFrame
+	c.SetReadDeadline(time.Now().Add(wsReadTimeout))
+	if err := websocket.JSON.Receive(c.Conn, &f); err != nil {
+		return "", 0, err
+	}
+	panicOfServerStatus()
+	takeWsToken(f.Op, c.ip, "")
+	var resumeToken string
+	switch f.Op {
+	case wsOpAuth:
+		sessionId, index = pubStub{}.AuthWithFormat(f.Token, f.Data)
+		resumeToken = pubStub{}.ResumeToken(sessionId)
+	case wsOpResume:
+		sessionId, index, resumeToken = pubStub{}.Resume(f.Token)
+	default:
+		return "", 0, errAuthFirst
+	}
+	log.Info("websocket auth, session %s", sessionId)
+	c.format = getFormatFromSession(sessionId)
+	err = c.send(newResponse(descAuthSuccess, map[string]interface{}{
+		"sessionId": sessionId,
+		"index":     index,
+		"resume":    resumeToken,
+	}))
+	return
+}
+
+// the methods of the ops, limited the same as the hprose api
+var wsOpMethods = map[string]string{
+	wsOpAuth:     "AuthWithFormat",
+	wsOpResume:   "Resume",
+	wsOpOperate:  "Operate",
+	wsOpChat:     "SendChat",
+	wsOpReady:    "SwitchReady",
+	wsOpKeyframe: "RequestKeyframe",
+	wsOpLang:     "SetLanguage",
+	wsOpPong:     "Ping",
+	wsOpQuit:     "Quit",
+}
+
+func takeWsToken(op, ip, user string) {
+	method, ok := wsOpMethods[op]
+	if !ok {
+		method = ratelimit.Default
+	}
+	if retryAfter, ok := pubRateLimiter.Take(method, ip, user); !ok {
+		panic(utils.ErrTooFrequent(retryAfter))
+	}
+}
+
+// call the pubStub, the panic is the error to the client
+func wsInvoke(f wsFrame, ip, sessionId string) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("%v", e)
+		}
+	}()
+	takeWsToken(f.Op, ip, rateLimitUser(sessionId))
+	switch f.Op {
+	case wsOpOperate:
+		pubStub{}.Operate(f.Data, sessionId)
+	case wsOpChat:
+		panicOfServerStatus()
+		pubStub{}.SendChat(f.Data, sessionId)
+	case wsOpReady:
+		panicOfServerStatus()
+		pubStub{}.SwitchReady(sessionId)
+	case wsOpKeyframe:
+		pubStub{}.RequestKeyframe(sessionId)
+	case wsOpQuit:
+		pubStub{}.Quit(sessionId)
+	case wsOpLang:
+		pubStub{}.SetLanguage(f.Data, sessionId)
+	case wsOpPong:
+	default:
+		return errcode.New(errcode.UnknownOp, f.Op)
+	}
+	return nil
+}
+
+// push the data of the table as they arrive, and ping the client
+func wsPush(c *wsConn, sessionId string, index int, done chan struct{}) {
+	tid, reader, belong := getTidFromSession(sessionId), getReaderFromSession(sessionId), belongOfSession(sessionId)
+	lastPing := clock.Now()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if !session.IsSessIdExist(sessionId) {
+			// the user quits or is kicked, close the connection to stop the reader
+			c.Close()
+			return
+		}
+		if clock.Now().Sub(lastPing) >= wsPingInterval {
+			lastPing = clock.Now()
+			if c.send(newResponse(descPing, clock.Now().Unix())) != nil {
+				return
+			}
+		}
+		res, next, resync := tableDatas.Wait(tid, index, reader, belong, wsPingInterval)
+		if resync {
+			res = resyncData(tid, next)
+		}
+		if next != index {
+			index = next
+			session.SetSession(sessKeyIndex, index, sessionId)
+		}
+		for _, r := range encodeAll(res, c.format) {
+			if c.sendEncoded(r) != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"code.google.com/p/go.net/websocket"
+	"github.com/gogames/go_tetris/utils"
+	"github.com/gogames/go_tetris/utils/ratelimit"
+)
+
+// a websocket client of the test server, authenticated by a join token of the user
+func dialWs(t *testing.T, addr string, uid, tid int) *websocket.Conn {
+	ws, err := websocket.Dial("ws://"+addr+wsPath, "", "http://"+addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := utils.GenerateToken(uid, "user", false, false, tid, tokenAudience)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := websocket.JSON.Send(ws, wsFrame{Op: wsOpAuth, Token: token, Data: "json"}); err != nil {
+		t.Fatal(err)
+	}
+	return ws
+}
+
+// read the frames until the description, the frames before it are returned as well
+func recvUntil(t *testing.T, ws *websocket.Conn, desc string) []responseData {
+	var frames []responseData
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg string
+		if err := websocket.Message.Receive(ws, &msg); err != nil {
+			t.Fatalf("can not receive %s, got %v: %v", desc, frames, err)
+		}
+		var r responseData
+		if err := json.Unmarshal([]byte(msg), &r); err != nil {
+			t.Fatal(err)
+		}
+		if frames = append(frames, r); r.Desc == desc {
+			return frames
+		}
+	}
+}
+
+// the client authenticates by the first frame, and the datas of the table and the pings are pushed
+func Test_WsAuthPush(t *testing.T) {
+	fc, ac := setupTest()
+	serverStatus = statusActive
+	tokenAudience = "game server"
+	if err := utils.SetTokenKeys("1:test key"); err != nil {
+		t.Fatal(err)
+	}
+	pubRateLimiter = ratelimit.New(pubRateLimits)
+	newTestTable(t, 107)
+	srv := httptest.NewServer(wsServer)
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	ws := dialWs(t, addr, 1, 107)
+	defer ws.Close()
+	auth := recvUntil(t, ws, descAuthSuccess)
+	res := auth[len(auth)-1].Data.(map[string]interface{})
+	sessionId, _ := res["sessionId"].(string)
+	if !session.IsSessIdExist(sessionId) || res["resume"] == "" {
+		t.Fatalf("unexpected auth %v", res)
+	}
+	if calls := ac.get(); len(calls) != 1 || calls[0] != "Join 107 1 false" {
+		t.Errorf("the auth server should be informed of the join, got %v", calls)
+	}
+	// the messages of joining are set after the session reads the datas
+	recvUntil(t, ws, descSysMsg)
+
+	// a wrong token is answered by the error, the connection is closed
+	other, _ := websocket.Dial("ws://"+addr+wsPath, "", "http://"+addr)
+	defer other.Close()
+	token, _ := utils.GenerateToken(2, "user", false, false, 107, tokenAudience)
+	websocket.JSON.Send(other, wsFrame{Op: wsOpAuth, Token: "x" + token})
+	if frames := recvUntil(t, other, descError); len(frames) != 1 {
+		t.Errorf("the wrong token should be answered by an error only, got %v", frames)
+	}
+
+	handleSysMsg(107, "hello")
+	if frames := recvUntil(t, ws, descSysMsg); frames[len(frames)-1].Data != "hello" {
+		t.Errorf("the data of the table should be pushed, got %v", frames)
+	}
+
+	// the ping is on the clock, the pusher wakes up by the data
+	fc.Advance(wsPingInterval)
+	handleSysMsg(107, "again")
+	recvUntil(t, ws, descPing)
+
+	// the kicked user is informed, and the connection is closed after the sessions are deleted
+	if err := (stub{}).Kick(107, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	recvUntil(t, ws, descKicked)
+	fc.Advance(kickDelay)
+	handleSysMsg(107, "bye")
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg string
+		if err := websocket.Message.Receive(ws, &msg); err != nil {
+			if strings.Contains(err.Error(), "timeout") {
+				t.Error("the connection of the kicked user should be closed")
+			}
+			break
+		}
+	}
+}
@@ -148,19 +148,42 @@ func (b *block) transparentBlock() {
 	}
 }
 
-func (b block) center() dot {
-	var x, y int
+// bounding box of the block
+func (b block) bounds() (minX, maxX, minY, maxY int) {
+	minX, maxX, minY, maxY = b[0].x, b[0].x, b[0].y, b[0].y
 	for _, v := range b {
-		x += v.x*2 + 1
-		y += v.y*2 + 1
+		if v.x < minX {
+			minX = v.x
+		}
+		if v.x > maxX {
+			maxX = v.x
+		}
+		if v.y < minY {
+			minY = v.y
+		}
+		if v.y > maxY {
+			maxY = v.y
+		}
 	}
-	return newDot(x/2/defaultNumOfDotsInABlock, y/2/defaultNumOfDotsInABlock, b[0].Color)
+	return
 }
 
+func floorHalf(v int) int { return v >> 1 }
+func ceilHalf(v int) int  { return (v + 1) >> 1 }
+
+// rotate 90 degree counter-clockwise by the center of the bounding box
+// the coordinates are doubled so that the center is always on the grid
+// a wide block rounds down and a tall block rounds up
+// so that rotating a block four times restores it
 func (b *block) rotate() block {
-	center := b.center()
+	minX, maxX, minY, maxY := b.bounds()
+	cx, cy := minX+maxX, minY+maxY
+	half := floorHalf
+	if maxX-minX <= maxY-minY {
+		half = ceilHalf
+	}
 	for i, v := range b {
-		b[i] = v.rotate(center)
+		b[i] = newDot(half(2*v.y-cy+cx), half(cx+cy-2*v.x), v.Color)
 	}
 	return *b
 }
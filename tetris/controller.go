@@ -3,6 +3,7 @@ package tetris
 
 import (
 	"container/ring"
+	"context"
 	"encoding/json"
 	"fmt"
 	"sync"
@@ -43,6 +44,15 @@ func (np *nextPieces) getOne(newP *piece) *piece {
 	return p
 }
 
+// copy of the next pieces, the ring keeps changing after the message is sent
+func (np *nextPieces) snapshot() []piece {
+	ps := make([]piece, 0, np.Len())
+	np.Do(func(v interface{}) {
+		ps = append(ps, *v.(*piece))
+	})
+	return ps
+}
+
 func (np *nextPieces) MarshalJSON() ([]byte, error) {
 	v := make([]interface{}, np.Len())
 	for i := 0; i < np.Len(); i++ {
@@ -61,6 +71,9 @@ type Game struct {
 
 	// the timer
 	timer *timer.Timer
+	// cancelled when the game stops, so that the falling goroutine quits
+	ctx    context.Context
+	cancel context.CancelFunc
 
 	// the pieces
 	activePiece *piece
@@ -68,7 +81,13 @@ type Game struct {
 	holded      bool
 	nextPieces  *nextPieces
 
-	// chan
+	// zone frames sent to the client
+	frames *frameEncoder
+
+	// event listeners
+	listeners []EventListener
+
+	// chan, fed by the chanListener which every game has
 	MsgChan      chan message // directly send to flash client
 	AttackChan   chan int
 	GameoverChan chan bool
@@ -79,6 +98,11 @@ type Game struct {
 }
 
 func NewGame(height, width, numOfNextPieces, interval int) (*Game, error) {
+	return NewGameWithClock(timer.RealClock, height, width, numOfNextPieces, interval)
+}
+
+// the pieces fall by the clock
+func NewGameWithClock(clock timer.Clock, height, width, numOfNextPieces, interval int) (*Game, error) {
 	if width < minWidth {
 		return nil, errWidth
 	}
@@ -92,23 +116,28 @@ func NewGame(height, width, numOfNextPieces, interval int) (*Game, error) {
 	}
 	g := &Game{
 		mainZone:     newZone(height, width),
-		timer:        timer.NewTimer(interval),
+		timer:        timer.NewTimerWithClock(clock, interval),
 		activePiece:  newPiece(width/2 - 2),
 		holdPiece:    nil,
 		holded:       false,
 		nextPieces:   np,
+		frames:       newFrameEncoder(),
 		MsgChan:      make(chan message, buffer),
 		GameoverChan: make(chan bool, 1),
 		AttackChan:   make(chan int, buffer),
 		BeingKOChan:  make(chan bool, 5),
 	}
+	g.listeners = []EventListener{chanListener{}}
+	g.ctx, g.cancel = context.WithCancel(context.Background())
 	go g.init()
 	return g, nil
 }
 
 func (g *Game) init() {
 	for {
-		g.timer.Wait()
+		if g.timer.WaitContext(g.ctx) != nil {
+			return
+		}
 		g.Lock()
 		g.check(true, false)
 		g.Unlock()
@@ -116,7 +145,11 @@ func (g *Game) init() {
 }
 
 func (g *Game) KoOpponent() {
+	g.Lock()
+	defer g.Unlock()
 	g.ko++
+	ko := g.ko
+	g.emit(func(l EventListener) { l.OnKO(g, ko) })
 	g.send(DescKo, g.ko)
 	g.send(DescAudio, audioKO())
 }
@@ -149,22 +182,24 @@ func (g *Game) check(moveDown, dropDown bool) {
 
 		// g.mainZone.putBlockOnMainZone(g.activePiece.block)
 		g.mainZone.putBlockOnZone(g.activePiece.block)
+		cells := blockCells(g.activePiece.block)
+		g.emit(func(l EventListener) { l.OnPieceLocked(g, cells) })
 
 		if lineSent := g.calculate(); lineSent > 0 {
 			g.scoreAdd(lineSent)
-			g.AttackChan <- lineSent
+			g.emit(func(l EventListener) { l.OnAttack(g, lineSent) })
 			g.send(DescAttack, lineSent)
 			g.send(DescLines, g.numOfLineSent)
 		}
 
 		g.activePiece = g.nextPieces.getOne(newPiece(g.mainZone.width()/2 - 2))
 
-		g.send(DescNextPiece, g.nextPieces)
+		g.send(DescNextPiece, g.nextPieces.snapshot())
 	}
 
 	// if being ko
 	if g.mainZone.beingKO() {
-		g.BeingKOChan <- true
+		g.emit(func(l EventListener) { l.OnTopOut(g) })
 		g.mainZone.removeStoneLines()
 		g.comboReset()
 	}
@@ -175,11 +210,23 @@ func (g *Game) check(moveDown, dropDown bool) {
 	// 		renderProjectionOfBlockOnZone(g.activePiece.block).
 	// 		renderBlockOnZone(g.activePiece.block))
 	// }
-	data := g.mainZone.render(g.activePiece.block)
-	g.send(DescZone, data)
+	// only the changed cells are sent, with a keyframe from time to time
+	frame := g.mainZone.render(g.activePiece.block)
+	if desc, val, ok := g.frames.encode(frame); ok {
+		g.send(desc, val)
+		g.emit(func(l EventListener) { l.OnZoneChanged(g, frame) })
+	}
 	// g.mainZone.unrender(g.activePiece.block)
 }
 
+// send a full zone keyframe
+// the client asks for it when it misses some deltas
+func (g *Game) RequestKeyframe() {
+	g.Lock()
+	defer g.Unlock()
+	g.send(DescZone, g.frames.keyframe(g.mainZone.render(g.activePiece.block)))
+}
+
 // get data
 func (g *Game) GetData() message {
 	return <-g.MsgChan
@@ -253,7 +300,7 @@ func (g *Game) Hold() {
 		g.activePiece, g.holdPiece = g.holdPiece, g.activePiece
 		g.activePiece.block = g.activePiece.resPosition
 	}
-	g.send(DescHoldedPiece, g.holdPiece)
+	g.send(DescHoldedPiece, *g.holdPiece)
 	g.check(false, false)
 }
 
@@ -272,16 +319,18 @@ func (g *Game) BeingAttacked(n int) {
 		g.mainZone.removeStoneLines()
 		return true
 	}(); ko {
-		g.BeingKOChan <- true
+		g.emit(func(l EventListener) { l.OnTopOut(g) })
 	}
 	g.check(false, false)
 }
 
 // start the game
 func (g *Game) Start() {
+	g.Lock()
+	defer g.Unlock()
 	g.timer.Start()
 	g.send(DescAudio, audioBackground())
-	g.send(DescNextPiece, g.nextPieces)
+	g.send(DescNextPiece, g.nextPieces.snapshot())
 }
 
 // pause the game
@@ -291,16 +340,20 @@ func (g *Game) Pause() {
 	g.send(DescAudio, audioBackground())
 }
 
-// stop the game
+// stop the game, it can not be started again
 func (g *Game) Stop() {
-	g.timer.Pause()
+	g.timer.Stop()
+	g.cancel()
 }
 
 // end the game
 func (g *Game) End() {
-	g.timer.Pause()
+	g.Lock()
+	defer g.Unlock()
+	g.timer.Stop()
+	g.cancel()
 	g.send(DescOver, true)
-	g.GameoverChan <- true
+	g.emit(func(l EventListener) { l.OnGameOver(g) })
 }
 
 // combo add one
@@ -360,6 +413,9 @@ func (g *Game) calculate() (lineSent int) {
 	}
 
 	g.mainZone.clearLinesByIndex(indice)
+	if total > 0 {
+		g.emit(func(lis EventListener) { lis.OnLinesCleared(g, l, hitBombs) })
+	}
 	// num of bombs hit and lines clear
 	// hitBombs := g.mainZone.checkHitBombs(g.activePiece.block)
 	if hitBombs > 0 {
@@ -0,0 +1,74 @@
+package tetris
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// the messages are encoded long after they are sent, like serveGame does
+// the zone in a message should never change after it is sent
+// run with -race
+func Test_MessageSnapshot(t *testing.T) {
+	g, err := NewGame(20, 10, 5, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Start()
+
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		ops := []func(){g.MoveLeft, g.MoveRight, g.Rotate, g.MoveDown, g.Hold, g.DropDown}
+		for i := 0; i < 2000; i++ {
+			ops[i%len(ops)]()
+			if i%200 == 0 {
+				g.BeingAttacked(1)
+			}
+		}
+	}()
+	go func() {
+		for {
+			select {
+			case <-g.AttackChan:
+			case <-g.BeingKOChan:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	type sent struct {
+		msg  message
+		json string
+	}
+	var keyframes []sent
+	for {
+		select {
+		case msg := <-g.MsgChan:
+			// the channel is drained slowly
+			time.Sleep(50 * time.Microsecond)
+			b, err := json.Marshal(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Description == DescZone {
+				keyframes = append(keyframes, sent{msg, string(b)})
+			}
+			continue
+		case <-done:
+		}
+		break
+	}
+	g.Stop()
+
+	if len(keyframes) == 0 {
+		t.Fatal("should receive at least one keyframe")
+	}
+	for _, k := range keyframes {
+		b, _ := json.Marshal(k.msg)
+		if string(b) != k.json {
+			t.Errorf("the keyframe changed after being sent:\n%s\n%s", k.json, b)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// game events for the observers, replays, stats, anti-cheat, bots...
+package tetris
+
+// the listener is called synchronously while the game is locked
+// it must not call the methods of the same game, and should not block
+// if it has to act on another game, like attacking the opponent, hand it over to another goroutine
+type EventListener interface {
+	// the active piece is locked on the zone
+	OnPieceLocked(g *Game, cells []Cell)
+	// lines are cleared, bombs are the stone lines cleared by hitting the bomb
+	OnLinesCleared(g *Game, lines, bombs int)
+	// lines are sent to attack the opponent
+	OnAttack(g *Game, lines int)
+	// ko the opponent, ko is the total number of ko
+	OnKO(g *Game, ko int)
+	// the stone lines reach the top, the player is ko by the opponent
+	OnTopOut(g *Game)
+	// the zone changes, the frame is a snapshot shared with the message, do not modify it
+	OnZoneChanged(g *Game, frame [][]Color)
+	// the game is over
+	OnGameOver(g *Game)
+}
+
+// embed it to implement only some of the callbacks
+type NopListener struct{}
+
+var _ EventListener = NopListener{}
+
+func (NopListener) OnPieceLocked(*Game, []Cell)    {}
+func (NopListener) OnLinesCleared(*Game, int, int) {}
+func (NopListener) OnAttack(*Game, int)            {}
+func (NopListener) OnKO(*Game, int)                {}
+func (NopListener) OnTopOut(*Game)                 {}
+func (NopListener) OnZoneChanged(*Game, [][]Color) {}
+func (NopListener) OnGameOver(*Game)               {}
+
+// feed the attack, being ko and game over channels
+type chanListener struct{ NopListener }
+
+func (chanListener) OnAttack(g *Game, lines int) { g.AttackChan <- lines }
+func (chanListener) OnTopOut(g *Game)            { g.BeingKOChan <- true }
+func (chanListener) OnGameOver(g *Game)          { g.GameoverChan <- true }
+
+// add a listener, the listener should be comparable, like a pointer, to be removed
+func (g *Game) AddListener(l EventListener) {
+	g.Lock()
+	defer g.Unlock()
+	g.listeners = append(g.listeners, l)
+}
+
+// remove a listener
+func (g *Game) RemoveListener(l EventListener) {
+	g.Lock()
+	defer g.Unlock()
+	for i, v := range g.listeners {
+		if v == l {
+			g.listeners = append(g.listeners[:i], g.listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatch the event to all listeners
+func (g *Game) emit(f func(l EventListener)) {
+	for _, l := range g.listeners {
+		f(l)
+	}
+}
+
+func blockCells(b block) []Cell {
+	cells := make([]Cell, 0, len(b))
+	for _, d := range b {
+		cells = append(cells, Cell{Y: d.y, X: d.x, Color: d.Color})
+	}
+	return cells
+}
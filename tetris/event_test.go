@@ -0,0 +1,80 @@
+package tetris
+
+import "testing"
+
+type recorder struct {
+	NopListener
+	locked, cleared, attack, ko, topOut, changed, over int
+}
+
+func (r *recorder) OnPieceLocked(g *Game, cells []Cell) {
+	if len(cells) != defaultNumOfDotsInABlock {
+		panic("a locked piece should have 4 cells")
+	}
+	r.locked++
+}
+func (r *recorder) OnLinesCleared(g *Game, lines, bombs int) { r.cleared += lines + bombs }
+func (r *recorder) OnAttack(g *Game, lines int)              { r.attack += lines }
+func (r *recorder) OnKO(g *Game, ko int)                     { r.ko = ko }
+func (r *recorder) OnTopOut(g *Game)                         { r.topOut++ }
+func (r *recorder) OnZoneChanged(g *Game, frame [][]Color)   { r.changed++ }
+func (r *recorder) OnGameOver(g *Game)                       { r.over++ }
+
+// drain the channels so that the game never blocks
+func drain(g *Game, done chan bool) {
+	for {
+		select {
+		case <-g.MsgChan:
+		case <-g.AttackChan:
+		case <-g.BeingKOChan:
+		case <-g.GameoverChan:
+		case <-done:
+			return
+		}
+	}
+}
+
+func Test_EventListener(t *testing.T) {
+	g, err := NewGame(20, 10, 5, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan bool)
+	defer close(done)
+	go drain(g, done)
+
+	r1, r2 := new(recorder), new(recorder)
+	g.AddListener(r1)
+	g.AddListener(r2)
+
+	g.MoveLeft()
+	if r1.changed != 1 {
+		t.Errorf("the zone changed once, but got %d", r1.changed)
+	}
+	g.DropDown()
+	g.DropDown()
+	if r1.locked != 2 || r2.locked != 2 {
+		t.Errorf("2 pieces are locked, but got %d and %d", r1.locked, r2.locked)
+	}
+
+	g.RemoveListener(r2)
+	for i := 0; i < 10; i++ {
+		g.BeingAttacked(defaultNumOfDotsInABlock)
+	}
+	if r1.topOut == 0 {
+		t.Errorf("should top out")
+	}
+	if r2.topOut != 0 {
+		t.Errorf("the removed listener should not be called")
+	}
+
+	g.KoOpponent()
+	g.KoOpponent()
+	if r1.ko != 2 {
+		t.Errorf("ko twice, but got %d", r1.ko)
+	}
+	g.End()
+	if r1.over != 1 || r2.over != 0 {
+		t.Errorf("game over should be sent to the listeners once, got %d and %d", r1.over, r2.over)
+	}
+}
@@ -26,11 +26,20 @@ func init() {
 func main() {
 	go handleInput()
 	go attack()
+	var zone [][]tetris.Color
 	for {
 		d := g.GetData()
 		switch d.Description {
-		case "zone":
-			renderScreen(d.Val.([][]tetris.Color))
+		case tetris.DescZone:
+			zone = d.Val.(tetris.ZoneKeyframe).Zone
+			renderScreen(zone)
+		case tetris.DescZoneDelta:
+			if zone == nil {
+				g.RequestKeyframe()
+				continue
+			}
+			d.Val.(tetris.ZoneDelta).Apply(zone)
+			renderScreen(zone)
 		default:
 			b, _ := json.Marshal(d.Val)
 			log.Printf("%s: %v", d.Description, string(b))
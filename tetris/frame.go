@@ -0,0 +1,94 @@
+// zone frames sent to the client
+// a keyframe carries the whole zone, a delta only carries the changed cells
+// both are numbered by seq so the client can detect a gap and ask for a keyframe
+package tetris
+
+import "encoding/json"
+
+// send a full keyframe every keyframeInterval frames
+const keyframeInterval = 64
+
+// full zone
+type ZoneKeyframe struct {
+	Seq  int       `json:"seq"`
+	Zone [][]Color `json:"zone"`
+}
+
+// changed cells since the previous frame
+type ZoneDelta struct {
+	Seq   int    `json:"seq"`
+	Cells []Cell `json:"cells"`
+}
+
+// a changed cell
+type Cell struct {
+	Y, X  int
+	Color Color
+}
+
+var _ json.Marshaler = Cell{}
+
+// [y, x, color] is much shorter than an object
+func (c Cell) MarshalJSON() ([]byte, error) {
+	return json.Marshal([3]int{c.Y, c.X, int(c.Color)})
+}
+
+func (c *Cell) UnmarshalJSON(b []byte) error {
+	var v [3]int
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	c.Y, c.X, c.Color = v[0], v[1], Color(v[2])
+	return nil
+}
+
+// apply the delta on the zone of the previous frame
+func (d ZoneDelta) Apply(z [][]Color) {
+	for _, c := range d.Cells {
+		if c.Y < 0 || c.Y >= len(z) || c.X < 0 || c.X >= len(z[c.Y]) {
+			continue
+		}
+		z[c.Y][c.X] = c.Color
+	}
+}
+
+// frame encoder remembers the last frame sent to the client
+type frameEncoder struct {
+	seq           int
+	last          [][]Color
+	sinceKeyframe int
+}
+
+func newFrameEncoder() *frameEncoder { return &frameEncoder{} }
+
+// encode the frame as a keyframe
+// the frame should be a snapshot from zone.render, it is shared by the keyframe and the encoder
+func (fe *frameEncoder) keyframe(frame [][]Color) ZoneKeyframe {
+	fe.seq++
+	fe.sinceKeyframe = 0
+	fe.last = frame
+	return ZoneKeyframe{Seq: fe.seq, Zone: frame}
+}
+
+// encode the frame, return the description and the value to send
+// ok is false if nothing changed since the last frame
+func (fe *frameEncoder) encode(frame [][]Color) (desc string, val interface{}, ok bool) {
+	if fe.last == nil || fe.sinceKeyframe >= keyframeInterval {
+		return DescZone, fe.keyframe(frame), true
+	}
+	cells := make([]Cell, 0)
+	for y := range frame {
+		for x, c := range frame[y] {
+			if fe.last[y][x] != c {
+				cells = append(cells, Cell{Y: y, X: x, Color: c})
+			}
+		}
+	}
+	fe.last = frame
+	if len(cells) == 0 {
+		return "", nil, false
+	}
+	fe.seq++
+	fe.sinceKeyframe++
+	return DescZoneDelta, ZoneDelta{Seq: fe.seq, Cells: cells}, true
+}
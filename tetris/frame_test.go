@@ -0,0 +1,77 @@
+package tetris
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func newTestFrame(h, w int) [][]Color {
+	f := make([][]Color, h)
+	for y := range f {
+		f[y] = make([]Color, w)
+	}
+	return f
+}
+
+// zone.render returns a new snapshot every time
+func copyFrame(frame [][]Color) [][]Color {
+	c := make([][]Color, len(frame))
+	for y := range frame {
+		c[y] = make([]Color, len(frame[y]))
+		copy(c[y], frame[y])
+	}
+	return c
+}
+
+func Test_FrameEncoder(t *testing.T) {
+	fe := newFrameEncoder()
+	frame := newTestFrame(4, 4)
+
+	// the first frame is always a keyframe
+	desc, val, ok := fe.encode(copyFrame(frame))
+	if !ok || desc != DescZone {
+		t.Fatalf("the first frame should be a keyframe, got %v", desc)
+	}
+	client := copyFrame(val.(ZoneKeyframe).Zone)
+
+	// nothing changed, nothing to send
+	if _, _, ok := fe.encode(copyFrame(frame)); ok {
+		t.Error("should not send anything if nothing changed")
+	}
+
+	frame[3][1] = Color(2)
+	frame[0][0] = constColorTransparent
+	desc, val, ok = fe.encode(copyFrame(frame))
+	if !ok || desc != DescZoneDelta {
+		t.Fatalf("should be a delta, got %v", desc)
+	}
+	d := val.(ZoneDelta)
+	if len(d.Cells) != 2 || d.Seq != 2 {
+		t.Errorf("delta should contain 2 cells with seq 2: %v", d)
+	}
+
+	// the client applies the delta decoded from json
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ZoneDelta
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	decoded.Apply(client)
+	if !reflect.DeepEqual(client, frame) {
+		t.Errorf("the client zone %v should be %v", client, frame)
+	}
+
+	// a keyframe is sent periodically
+	for i := 0; i < keyframeInterval; i++ {
+		frame[i%4][i%3] = Color(i%7 + 1)
+		frame[(i+1)%4][(i+1)%3] = constColorNothing
+		desc, _, ok = fe.encode(copyFrame(frame))
+	}
+	if !ok || desc != DescZone {
+		t.Errorf("should send a keyframe after %d deltas", keyframeInterval)
+	}
+}
@@ -15,6 +15,11 @@ func (d message) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// the description and the value, for the binary codec
+func (d message) Parts() (string, interface{}) {
+	return d.Description, d.Val
+}
+
 func NewMessage(desc string, val interface{}) message {
 	return message{
 		Description: desc,
@@ -24,13 +29,14 @@ func NewMessage(desc string, val interface{}) message {
 
 // descriptions
 const (
-	DescNextPiece   = "next"   // next piece change
-	DescHoldedPiece = "hold"   // hold piece change
-	DescZone        = "zone"   // zone change
-	DescAudio       = "audio"  // audio play
-	DescAttack      = "attack" // send lines to attack opponent (send line, or T Z spin)
-	DescLines       = "lines"  // number of send lines changed
-	DescCombo       = "combo"  // combo number changed
+	DescNextPiece   = "next"      // next piece change
+	DescHoldedPiece = "hold"      // hold piece change
+	DescZone        = "zone"      // zone keyframe, the whole zone
+	DescZoneDelta   = "zoneDelta" // zone change, only the changed cells
+	DescAudio       = "audio"     // audio play
+	DescAttack      = "attack"    // send lines to attack opponent (send line, or T Z spin)
+	DescLines       = "lines"     // number of send lines changed
+	DescCombo       = "combo"     // combo number changed
 	DescBomb        = "bomb"
 	DescKo          = "ko"       // ko the opponent
 	DescBeingKo     = "beingKo"  // ko by the opponent
@@ -2,12 +2,31 @@ package tetris
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 )
 
 const defaultNumOfDotsInABlock = 4
 
-var randSeed = rand.New(rand.NewSource(time.Now().UnixNano()))
+// the games of all tables share the seed, the source should be locked
+var randSeed = rand.New(&lockedSource{src: rand.NewSource(time.Now().UnixNano())})
+
+type lockedSource struct {
+	sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.Lock()
+	defer s.Unlock()
+	s.src.Seed(seed)
+}
 
 // rotate dot d 90 degree by dot origin counter-clockwise
 func rotate(d, origin dot) dot {
@@ -11,14 +11,14 @@ var testUtilsData = []testUtilsDataStruct{
 	testUtilsDataStruct{
 		isContiguous: true,
 		isOverlapped: false,
-		d1:           newDot(0, 0, newColor(1)),
-		d2:           newDot(1, 0, newColor(1)),
+		d1:           newDot(0, 0, Color(1)),
+		d2:           newDot(1, 0, Color(1)),
 	},
 	testUtilsDataStruct{
 		isContiguous: false,
 		isOverlapped: true,
-		d1:           newDot(0, 0, newColor(1)),
-		d2:           newDot(0, 0, newColor(1)),
+		d1:           newDot(0, 0, Color(1)),
+		d2:           newDot(0, 0, Color(1)),
 	},
 }
 
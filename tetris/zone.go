@@ -1,34 +1,27 @@
 // game zone
 package tetris
 
-var (
-	constClearLine []Color
-)
-
 type zone struct {
-	h, w     int
-	data     [][]Color
-	wrapZone [][]Color
+	h, w int
+	data [][]Color
+	// every zone has its own clear line, zones may have different width
+	clearLine []Color
 }
 
 func newZone(height, width int) *zone {
-	constClearLine = make([]Color, width)
-	for i := range constClearLine {
-		constClearLine[i] = constColorNothing
+	clearLine := make([]Color, width)
+	for i := range clearLine {
+		clearLine[i] = constColorNothing
 	}
 	z := make([][]Color, height)
 	for i := range z {
 		z[i] = make([]Color, width)
 	}
-	w := make([][]Color, height)
-	for i := range w {
-		w[i] = make([]Color, width)
-	}
 	return &zone{
-		h:        height,
-		w:        width,
-		data:     z,
-		wrapZone: w,
+		h:         height,
+		w:         width,
+		data:      z,
+		clearLine: clearLine,
 	}
 }
 
@@ -148,7 +141,7 @@ func (z *zone) clearLinesByIndex(indice []int) {
 				z.setLine(i, z.getLineByHeight(i-1))
 			}
 		}
-		z.setLine(0, constClearLine)
+		z.setLine(0, z.clearLine)
 	}
 }
 
@@ -290,6 +283,8 @@ func (z zone) canBlockRotate(b block) (block, bool) {
 }
 
 // render zone for AS client
+// the frame is a new snapshot every time, it is never modified after being returned
+// so it is safe to send it over the channel and encode it later
 func (z *zone) render(b block) [][]Color {
 	// render projection of the block
 	projB := b
@@ -300,17 +295,19 @@ func (z *zone) render(b block) [][]Color {
 	// z.putBlockOnZone(projB)
 	// render active block
 	// z.putBlockOnZone(b)
-	for y := 0; y < z.height(); y++ {
-		for x := 0; x < z.width(); x++ {
-			z.wrapZone[y][x] = z.getDotByCoor(y, x)
-		}
+	// one backing array for the whole frame
+	cells := make([]Color, z.height()*z.width())
+	frame := make([][]Color, z.height())
+	for y := range frame {
+		frame[y] = cells[y*z.width() : (y+1)*z.width() : (y+1)*z.width()]
+		copy(frame[y], z.getLineByHeight(y))
 	}
 	for _, d := range projB {
-		z.wrapZone[d.y][d.x] = d.Color
+		frame[d.y][d.x] = d.Color
 	}
 	for _, d := range b {
-		z.wrapZone[d.y][d.x] = d.Color
+		frame[d.y][d.x] = d.Color
 	}
 	// return z.data
-	return z.wrapZone
+	return frame
 }
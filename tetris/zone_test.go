@@ -0,0 +1,218 @@
+package tetris
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// count the cells which are not nothing
+func numOfCells(z *zone) (n int) {
+	for y := 0; y < z.height(); y++ {
+		for x := 0; x < z.width(); x++ {
+			if !z.getDotByCoor(y, x).isNothing() {
+				n++
+			}
+		}
+	}
+	return
+}
+
+func isBlockInZone(z *zone, b block) bool {
+	return !b.outBoundTop(0) && !b.outBoundButtom(z.height()-1) &&
+		!b.outBoundLeft(0) && !b.outBoundRight(z.width()-1)
+}
+
+// check the invariants of the zone
+func checkZone(t *testing.T, z *zone) {
+	if len(z.data) != z.height() {
+		t.Fatalf("the zone should have %d lines, but it has %d", z.height(), len(z.data))
+	}
+	for y, l := range z.data {
+		if len(l) != z.width() {
+			t.Fatalf("the line %d should have %d cells, but it has %d", y, z.width(), len(l))
+		}
+		for x, c := range l {
+			if !c.isNothing() && !c.isActiveColor() && !c.isStone() && !c.isBomb() {
+				t.Fatalf("unexpected color %v at y: %d, x: %d", c, y, x)
+			}
+		}
+	}
+	// stone lines are always at the bottom
+	stone := false
+	for y := 0; y < z.height(); y++ {
+		if z.isStoneLine(y) {
+			stone = true
+			if z.getBombXCoor(y) == -1 {
+				t.Fatalf("the stone line %d has no bomb", y)
+			}
+		} else if stone {
+			t.Fatalf("the line %d is above the bottom but below a stone line", y)
+		}
+	}
+}
+
+// rotating a block four times restores the block
+func Test_RotateFourTimes(t *testing.T) {
+	for i, b := range blocks {
+		for dx := -3; dx <= 3; dx++ {
+			for dy := -3; dy <= 3; dy++ {
+				origin := b
+				for x := dx; x > 0; x-- {
+					origin = origin.moveRight()
+				}
+				for y := dy; y > 0; y-- {
+					origin = origin.moveDown()
+				}
+				r := origin
+				for n := 0; n < 4; n++ {
+					r = r.rotate()
+				}
+				if r != origin {
+					t.Errorf("block %d at %d, %d: %v is not restored after rotating four times: %v", i, dx, dy, origin, r)
+				}
+			}
+		}
+	}
+}
+
+// a rotated block keeps its shape and is always in the zone
+func Test_CanBlockRotate(t *testing.T) {
+	z := newZone(minHeight, minWidth)
+	for i, b := range blocks {
+		for x := 0; x < z.width(); x++ {
+			for y := 0; y < z.height(); y++ {
+				p := b
+				for p.outBoundRight(z.width() - 1) {
+					p = p.moveLeft()
+				}
+				for n := 0; n < x && !p.outBoundRight(z.width()-2); n++ {
+					p = p.moveRight()
+				}
+				for n := 0; n < y && !p.outBoundButtom(z.height()-2); n++ {
+					p = p.moveDown()
+				}
+				r, _ := z.canBlockRotate(p)
+				if !isBlockInZone(z, r) {
+					t.Errorf("block %d %v is out of the zone after rotation: %v", i, p, r)
+				}
+				if numOfDistinctDots(r) != defaultNumOfDotsInABlock {
+					t.Errorf("block %d %v overlaps itself after rotation: %v", i, p, r)
+				}
+			}
+		}
+	}
+}
+
+func numOfDistinctDots(b block) int {
+	m := make(map[[2]int]bool)
+	for _, d := range b {
+		m[[2]int{d.x, d.y}] = true
+	}
+	return len(m)
+}
+
+// play a random game on the zone, the ops come from the bytes
+// 0: move left, 1: move right, 2: move down, 3: rotate, 4: drop and clear, 5: add stone lines
+func playZone(t *testing.T, z *zone, ops []byte) {
+	r := rand.New(rand.NewSource(int64(len(ops))))
+	newActive := func() block {
+		b := blocks[r.Intn(len(blocks))]
+		for n := 0; n < z.width()/2-2; n++ {
+			b = b.moveRight()
+		}
+		return b
+	}
+	b := newActive()
+	for _, op := range ops {
+		if !z.canPutBlockOnZone(b) {
+			return
+		}
+		switch op % 6 {
+		case 0:
+			if z.canBlockMoveLeft(b) {
+				b = b.moveLeft()
+			}
+		case 1:
+			if z.canBlockMoveRight(b) {
+				b = b.moveRight()
+			}
+		case 2:
+			if z.canBlockMoveDown(b) {
+				b = b.moveDown()
+			}
+		case 3:
+			if rb, ok := z.canBlockRotate(b); ok {
+				if !isBlockInZone(z, rb) {
+					t.Fatalf("rotated block is out of the zone: %v", rb)
+				}
+				b = rb
+			}
+		case 4:
+			b = z.dropBlockOnZone(b)
+			z.putBlockOnZone(b)
+			before := numOfCells(z)
+			indice, lines, bombs := z.calculateLinesToClear(b)
+			if len(indice) != lines+bombs {
+				t.Fatalf("%d lines to clear, but %d lines and %d bombs", len(indice), lines, bombs)
+			}
+			cleared := 0
+			for _, y := range indice {
+				cleared += z.width()
+				if z.isStoneLine(y) {
+					continue
+				}
+				for x := 0; x < z.width(); x++ {
+					if !z.getDotByCoor(y, x).isActiveColor() {
+						t.Fatalf("the line %d is not full but cleared", y)
+					}
+				}
+			}
+			z.clearLinesByIndex(indice)
+			// cell count is conserved across line clears
+			if after := numOfCells(z); after != before-cleared {
+				t.Fatalf("%d cells before clearing %v, %d cells after", before, indice, after)
+			}
+			b = newActive()
+		case 5:
+			n := int(op/6)%3 + 1
+			if z.canHoldStoneLines(n) {
+				before := numOfCells(z)
+				z.addStoneLinesToZone(n)
+				if after := numOfCells(z); after != before+n*z.width() {
+					t.Fatalf("%d cells before adding %d stone lines, %d cells after", before, n, after)
+				}
+			} else {
+				z.removeStoneLines()
+			}
+		}
+		if !isBlockInZone(z, b) {
+			t.Fatalf("the active block is out of the zone: %v", b)
+		}
+		checkZone(t, z)
+	}
+}
+
+func Test_PlayZone(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		ops := make([]byte, 500)
+		r.Read(ops)
+		playZone(t, newZone(minHeight+r.Intn(20), minWidth+r.Intn(12)), ops)
+	}
+}
+
+// the first two bytes are the height and width of the zone
+// the third byte is the width of another zone, like another table on the same server
+func FuzzZone(f *testing.F) {
+	f.Add([]byte{16, 6, 6, 4, 4, 3, 0, 2, 4, 5, 4})
+	f.Add([]byte{0, 0, 0, 3, 3, 3, 3, 4, 11, 4, 4})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) < 3 {
+			return
+		}
+		height := minHeight + int(data[0])%24
+		z := newZone(height, minWidth+int(data[1])%16)
+		newZone(height, minWidth+int(data[2])%16)
+		playZone(t, z, data[3:])
+	})
+}
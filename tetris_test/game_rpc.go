@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/gogames/go_tetris/codec"
 	"github.com/hprose/hprose-go/hprose"
 )
 
@@ -17,13 +18,13 @@ var (
 )
 
 type gStub struct {
-	Auth        func(string) (string, int, error)
-	SwitchReady func(string) error
-	SendChat    func(string, string) error
-	Operate     func(string, string) error
-	Quit        func(string) error
-	Ping        func(string) error
-	GetData     func(int, string) ([]string, int, error)
+	AuthWithFormat func(string, string) (string, int, error)
+	SwitchReady    func(string) error
+	SendChat       func(string, string) error
+	Operate        func(string, string) error
+	Quit           func(string) error
+	Ping           func(string) error
+	GetData        func(int, string) ([][]byte, int, error)
 }
 
 func joinGameServer(host string, token string) {
@@ -34,7 +35,8 @@ func joinGameServer(host string, token string) {
 	gClient.UseService(&gS)
 
 	var err error
-	gSessionId, gIndex, err = gS.Auth(token)
+	// the binary frames, see codec
+	gSessionId, gIndex, err = gS.AuthWithFormat(token, codec.FormatBinary)
 	if err != nil {
 		fmt.Printf("get error when validate token: %v\n", err)
 		return
@@ -83,7 +85,12 @@ func getData() {
 		}
 		gIndex = i
 		for _, v := range vals {
-			fmt.Printf("get data %s\n", v)
+			m, err := codec.Decode(v)
+			if err != nil {
+				fmt.Printf("can not decode data %v: %v\n", v, err)
+				continue
+			}
+			fmt.Printf("get data %s %s %v\n", m.Desc, m.Sub, m.Data)
 		}
 	}
 }
@@ -0,0 +1,88 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package timer
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+const (
+	numOfTables     = 1000
+	timersPerTable  = 3 // the table timer and two games
+	benchmarkPeriod = 100 * time.Millisecond
+)
+
+func cpuTime() time.Duration {
+	var ru syscall.Rusage
+	syscall.Getrusage(syscall.RUSAGE_SELF, &ru)
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+}
+
+// run the tables, report cpu time per second
+func benchmarkTables(b *testing.B, start func(interval time.Duration) (stop func())) {
+	stops := make([]func(), 0, numOfTables*timersPerTable)
+	for i := 0; i < numOfTables; i++ {
+		stops = append(stops, start(time.Second), start(500*time.Millisecond), start(500*time.Millisecond))
+	}
+	defer func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}()
+	b.ResetTimer()
+	cpu, wall := cpuTime(), time.Now()
+	for i := 0; i < b.N; i++ {
+		time.Sleep(benchmarkPeriod)
+	}
+	b.ReportMetric(float64(cpuTime()-cpu)/float64(time.Since(wall)), "cpu/s")
+}
+
+// the timers on the scheduler
+func Benchmark_1000TablesScheduler(b *testing.B) {
+	benchmarkTables(b, func(interval time.Duration) func() {
+		t := NewTimer(int(interval / time.Millisecond))
+		t.Start()
+		done := make(chan bool)
+		go func() {
+			for {
+				select {
+				case <-t.tick:
+				case <-done:
+					return
+				}
+			}
+		}()
+		return func() {
+			t.Stop()
+			close(done)
+		}
+	})
+}
+
+// the timers as they were, every timer polls a 10ms ticker in its own goroutine
+func Benchmark_1000TablesTicker(b *testing.B) {
+	benchmarkTables(b, func(interval time.Duration) func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		done := make(chan bool)
+		go func() {
+			current, ticks := time.Duration(0), 0
+			for {
+				select {
+				case <-ticker.C:
+					if current = (current + 10*time.Millisecond) % interval; current == 0 {
+						ticks++
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+		return func() {
+			ticker.Stop()
+			close(done)
+		}
+	})
+}
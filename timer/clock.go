@@ -0,0 +1,42 @@
+package timer
+
+import "time"
+
+// clock is where the timers get the time
+// use the real clock on servers, the fake clock in tests
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+	// call f in its own goroutine after d
+	AfterFunc(d time.Duration, f func()) Stopper
+}
+
+// stop the function from being called, false if it is already called or stopped
+type Stopper interface {
+	Stop() bool
+}
+
+// ticker of the clock
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// the real clock, based on the time package
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+func (realClock) AfterFunc(d time.Duration, f func()) Stopper {
+	return time.AfterFunc(d, f)
+}
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
@@ -0,0 +1,184 @@
+package timer
+
+import (
+	"sync"
+	"time"
+)
+
+// fake clock for tests, the time only goes on by Advance
+// unlike the real ticker, the fake ticker never drops a tick,
+// Advance blocks until the tick is received or the ticker is stopped
+// the functions of AfterFunc are called by Advance in order, not in their own goroutines,
+// so that everything driven by them has been done when Advance returns
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// a sleeper, an after channel, a ticker or a function
+type fakeWaiter struct {
+	until  time.Time
+	period time.Duration // only tickers have period
+	c      chan time.Time
+	done   chan struct{} // closed when the ticker is stopped
+	f      func()
+}
+
+var _ Clock = NewFakeClock(time.Time{})
+
+func NewFakeClock(now time.Time) *FakeClock {
+	fc := &FakeClock{now: now}
+	fc.cond = sync.NewCond(&fc.mu)
+	return fc
+}
+
+func (fc *FakeClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+func (fc *FakeClock) Sleep(d time.Duration) {
+	<-fc.After(d)
+}
+
+func (fc *FakeClock) After(d time.Duration) <-chan time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	w := &fakeWaiter{until: fc.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.c <- fc.now
+		return w.c
+	}
+	fc.addWaiter(w)
+	return w.c
+}
+
+func (fc *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	w := &fakeWaiter{
+		until:  fc.now.Add(d),
+		period: d,
+		c:      make(chan time.Time),
+		done:   make(chan struct{}),
+	}
+	fc.addWaiter(w)
+	return &fakeTicker{fc: fc, w: w}
+}
+
+func (fc *FakeClock) AfterFunc(d time.Duration, f func()) Stopper {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	w := &fakeWaiter{until: fc.now.Add(d), f: f}
+	fc.addWaiter(w)
+	return &fakeStopper{fc: fc, w: w}
+}
+
+// move the time forward, fire the sleepers, the after channels, the tickers and the functions on the way in order
+func (fc *FakeClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	end := fc.now.Add(d)
+	fc.mu.Unlock()
+	for {
+		fc.mu.Lock()
+		w := fc.next(end)
+		if w == nil {
+			fc.now = end
+			fc.mu.Unlock()
+			return
+		}
+		fc.now = w.until
+		now := fc.now
+		if w.period > 0 {
+			w.until = w.until.Add(w.period)
+		} else {
+			fc.removeWaiter(w)
+		}
+		fc.mu.Unlock()
+
+		switch {
+		case w.f != nil:
+			w.f()
+		case w.period > 0:
+			select {
+			case w.c <- now:
+			case <-w.done:
+			}
+		default:
+			w.c <- now
+		}
+	}
+}
+
+// block until there are at least n sleepers, after channels and tickers
+// a test calls it to make sure the goroutines are waiting before advancing the clock
+func (fc *FakeClock) BlockUntil(n int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for len(fc.waiters) < n {
+		fc.cond.Wait()
+	}
+}
+
+// the earliest waiter which should fire before end
+func (fc *FakeClock) next(end time.Time) *fakeWaiter {
+	var w *fakeWaiter
+	for _, v := range fc.waiters {
+		if v.until.After(end) {
+			continue
+		}
+		if w == nil || v.until.Before(w.until) {
+			w = v
+		}
+	}
+	return w
+}
+
+func (fc *FakeClock) addWaiter(w *fakeWaiter) {
+	fc.waiters = append(fc.waiters, w)
+	fc.cond.Broadcast()
+}
+
+func (fc *FakeClock) removeWaiter(w *fakeWaiter) bool {
+	for i, v := range fc.waiters {
+		if v == w {
+			fc.waiters = append(fc.waiters[:i], fc.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeStopper struct {
+	fc *FakeClock
+	w  *fakeWaiter
+}
+
+func (s *fakeStopper) Stop() bool {
+	s.fc.mu.Lock()
+	defer s.fc.mu.Unlock()
+	return s.fc.removeWaiter(s.w)
+}
+
+type fakeTicker struct {
+	fc   *FakeClock
+	w    *fakeWaiter
+	once sync.Once
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.w.c }
+
+func (t *fakeTicker) Stop() {
+	t.once.Do(func() {
+		t.fc.mu.Lock()
+		defer t.fc.mu.Unlock()
+		t.fc.removeWaiter(t.w)
+		close(t.w.done)
+	})
+}
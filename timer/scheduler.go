@@ -0,0 +1,135 @@
+package timer
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// all timers of a clock share one scheduler
+// the scheduler keeps the timers in a heap by the time of their next tick,
+// and only wakes up when the earliest one is due
+type scheduler struct {
+	mu     sync.Mutex
+	clock  Clock
+	items  itemHeap
+	wake   Stopper
+	wakeAt time.Time
+}
+
+var (
+	schedulersMu sync.Mutex
+	schedulers   = make(map[Clock]*scheduler)
+)
+
+func schedulerOf(clock Clock) *scheduler {
+	schedulersMu.Lock()
+	defer schedulersMu.Unlock()
+	s, ok := schedulers[clock]
+	if !ok {
+		s = &scheduler{clock: clock}
+		schedulers[clock] = s
+	}
+	return s
+}
+
+// the next tick of a timer
+type item struct {
+	t     *Timer
+	at    time.Time
+	index int // index in the heap, -1 if not scheduled
+}
+
+// schedule the next tick of the item at the time
+func (s *scheduler) schedule(it *item, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it.at = at
+	if it.index < 0 {
+		heap.Push(&s.items, it)
+	} else {
+		heap.Fix(&s.items, it.index)
+	}
+	s.arm()
+}
+
+// cancel the next tick of the item
+func (s *scheduler) cancel(it *item) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if it.index >= 0 {
+		heap.Remove(&s.items, it.index)
+	}
+	s.arm()
+}
+
+// check if the item is scheduled again since it is due
+func (s *scheduler) isScheduled(it *item) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return it.index >= 0
+}
+
+// wake up at the earliest tick
+func (s *scheduler) arm() {
+	if len(s.items) == 0 {
+		if s.wake != nil {
+			s.wake.Stop()
+			s.wake = nil
+		}
+		return
+	}
+	at := s.items[0].at
+	if s.wake != nil {
+		if s.wakeAt.Equal(at) {
+			return
+		}
+		s.wake.Stop()
+	}
+	s.wakeAt = at
+	s.wake = s.clock.AfterFunc(at.Sub(s.clock.Now()), s.run)
+}
+
+// pop the due items and fire their timers
+func (s *scheduler) run() {
+	s.mu.Lock()
+	now := s.clock.Now()
+	due := make([]*item, 0)
+	for len(s.items) > 0 && !s.items[0].at.After(now) {
+		due = append(due, heap.Pop(&s.items).(*item))
+	}
+	s.wake = nil
+	s.arm()
+	s.mu.Unlock()
+
+	// the timers lock themselves and schedule again
+	for _, it := range due {
+		it.t.fire(it)
+	}
+}
+
+type itemHeap []*item
+
+func (h itemHeap) Len() int           { return len(h) }
+func (h itemHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h itemHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *itemHeap) Push(x interface{}) {
+	it := x.(*item)
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *itemHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	it.index = -1
+	*h = old[:n-1]
+	return it
+}
@@ -1,15 +1,16 @@
 // could be a better timer, pause supported
-// may not be accurate, but fair enough for me
+// the ticks are scheduled by deadline, so they do not drift, and the time left is kept across pause
+// the timers do not have their own goroutines, they are driven by the scheduler of the clock
 package timer
 
 import (
+	"context"
 	"sync"
 	"time"
 )
 
 const (
 	defaultInterval = 500
-	tickFrequency   = 10
 )
 
 func i2Duration(i int) time.Duration {
@@ -18,63 +19,73 @@ func i2Duration(i int) time.Duration {
 
 type Timer struct {
 	sync.Mutex
-	timerInterval, currentTick int // in ms
-	ticker                     *time.Ticker
-	isPaused                   bool
-	tick                       chan bool
+	interval  time.Duration
+	isPaused  bool
+	isStopped bool
+	clock     Clock
+	s         *scheduler
+	next      *item
+	// the time left to the next tick when the timer is paused
+	remaining time.Duration
+	tick      chan bool
 }
 
 func NewTimer(intervalInMs ...int) *Timer {
+	return NewTimerWithClock(RealClock, intervalInMs...)
+}
+
+// the timer ticks by the clock
+func NewTimerWithClock(clock Clock, intervalInMs ...int) *Timer {
 	var interval int = defaultInterval
 	if len(intervalInMs) > 0 {
 		interval = intervalInMs[0]
 	}
 	t := &Timer{
-		timerInterval: interval,
-		currentTick:   tickFrequency,
-		ticker:        time.NewTicker(i2Duration(tickFrequency)),
-		tick:          make(chan bool),
-		isPaused:      true,
+		interval:  i2Duration(interval),
+		isPaused:  true,
+		clock:     clock,
+		s:         schedulerOf(clock),
+		remaining: i2Duration(interval),
+		// a tick is kept until it is waited, the ticks are not piled up if nobody waits
+		tick: make(chan bool, 1),
 	}
-	return t.init()
-}
-
-func (t *Timer) init() *Timer {
-	go t.startTick()
+	t.next = &item{t: t, index: -1}
 	return t
 }
 
-func (t *Timer) setTick() {
+// called by the scheduler when the next tick is due
+func (t *Timer) fire(it *item) {
 	t.Lock()
 	defer t.Unlock()
-	if !t.isPaused {
-		t.currentTick = (t.currentTick + tickFrequency) % t.timerInterval
+	// paused, or reset after it is due
+	if t.isPaused || t.s.isScheduled(it) {
+		return
 	}
-}
-
-func (t *Timer) shouldTick() bool {
-	t.Lock()
-	defer t.Unlock()
-	return !t.isPaused && t.currentTick == 0
-}
-
-func (t *Timer) startTick() {
-	for {
-		select {
-		case <-t.ticker.C:
-			t.setTick()
-			if t.shouldTick() {
-				t.tick <- true
-			}
-		}
+	select {
+	case t.tick <- true:
+	default:
+	}
+	// the next tick is one interval after this one, not after now, so the timer does not drift
+	// if the ticks are late for more than one interval, skip the missed ones
+	next := it.at.Add(t.interval)
+	if now := t.clock.Now(); !next.After(now) {
+		next = next.Add((now.Sub(next)/t.interval + 1) * t.interval)
 	}
+	t.s.schedule(it, next)
 }
 
-// pause
+// pause, the time left to the next tick is kept
 func (t *Timer) Pause() {
 	t.Lock()
 	defer t.Unlock()
+	if t.isPaused {
+		return
+	}
 	t.isPaused = true
+	t.s.cancel(t.next)
+	if t.remaining = t.next.at.Sub(t.clock.Now()); t.remaining < 0 {
+		t.remaining = 0
+	}
 }
 
 func (t *Timer) IsPaused() bool {
@@ -83,18 +94,55 @@ func (t *Timer) IsPaused() bool {
 	return t.isPaused
 }
 
-// break pause
+// break pause, the next tick comes after the time left when it is paused
 func (t *Timer) Start() {
 	t.Lock()
 	defer t.Unlock()
+	if !t.isPaused || t.isStopped {
+		return
+	}
 	t.isPaused = false
+	t.s.schedule(t.next, t.clock.Now().Add(t.remaining))
 }
 
-// reset
+// reset, the next tick is one interval later
 func (t *Timer) Reset() {
 	t.Lock()
 	defer t.Unlock()
-	t.currentTick = tickFrequency
+	if t.isPaused {
+		t.remaining = t.interval
+		return
+	}
+	t.s.schedule(t.next, t.clock.Now().Add(t.interval))
+}
+
+// change the interval, the current interval is changed as well
+// e.g. the interval changes from 1000ms to 600ms after 500ms, the next tick comes 100ms later
+func (t *Timer) SetInterval(intervalInMs int) {
+	t.Lock()
+	defer t.Unlock()
+	interval := i2Duration(intervalInMs)
+	if interval <= 0 {
+		return
+	}
+	diff := interval - t.interval
+	t.interval = interval
+	if t.isPaused {
+		if t.remaining += diff; t.remaining < 0 {
+			t.remaining = 0
+		}
+		return
+	}
+	next := t.next.at.Add(diff)
+	if now := t.clock.Now(); next.Before(now) {
+		next = now
+	}
+	t.s.schedule(t.next, next)
+}
+
+// the channel of the ticks, a tick is kept until it is received
+func (t *Timer) C() <-chan bool {
+	return t.tick
 }
 
 // wait for next tick
@@ -102,9 +150,21 @@ func (t *Timer) Wait() {
 	<-t.tick
 }
 
-// stop
+// wait for next tick, or until the context is done
+func (t *Timer) WaitContext(ctx context.Context) error {
+	select {
+	case <-t.tick:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stop, the timer never ticks again
 func (t *Timer) Stop() {
 	t.Lock()
 	defer t.Unlock()
-	t.ticker.Stop()
+	t.isPaused = true
+	t.isStopped = true
+	t.s.cancel(t.next)
 }
@@ -0,0 +1,156 @@
+package timer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// advance the fake clock millisecond by millisecond, return the number of ticks on the way
+func advance(fc *FakeClock, t *Timer, d time.Duration) (n int) {
+	for ; d > 0; d -= time.Millisecond {
+		fc.Advance(time.Millisecond)
+		select {
+		case <-t.tick:
+			n++
+		default:
+		}
+	}
+	return
+}
+
+func Test_FakeClockTimer(t *testing.T) {
+	fc := NewFakeClock(time.Unix(0, 0))
+	tm := NewTimerWithClock(fc, 100)
+
+	if n := advance(fc, tm, time.Second); n != 0 {
+		t.Errorf("the timer is paused, but it ticks %d times", n)
+	}
+
+	tm.Start()
+	if n := advance(fc, tm, time.Second); n != 10 {
+		t.Errorf("the timer should tick 10 times in a second, but it ticks %d times", n)
+	}
+
+	// reset delays the next tick
+	advance(fc, tm, 50*time.Millisecond)
+	tm.Reset()
+	if n := advance(fc, tm, 99*time.Millisecond); n != 0 {
+		t.Errorf("the timer is reset, but it ticks %d times", n)
+	}
+	if n := advance(fc, tm, time.Millisecond); n != 1 {
+		t.Errorf("the timer should tick once after reset, but it ticks %d times", n)
+	}
+
+	// the ticks are not piled up
+	fc.Advance(time.Second)
+	if n := advance(fc, tm, time.Millisecond); n != 1 {
+		t.Errorf("the ticks should not be piled up, got %d", n)
+	}
+
+	if now := fc.Now(); !now.Equal(time.Unix(0, 0).Add(3151 * time.Millisecond)) {
+		t.Errorf("unexpected now %v", now)
+	}
+	tm.Stop()
+	tm.Start()
+	if n := advance(fc, tm, time.Second); n != 0 {
+		t.Errorf("the timer is stopped, but it ticks %d times", n)
+	}
+}
+
+func Test_FakeClockSleep(t *testing.T) {
+	fc := NewFakeClock(time.Unix(0, 0))
+	done := make(chan bool)
+	go func() {
+		fc.Sleep(time.Minute)
+		close(done)
+	}()
+	fc.BlockUntil(1)
+	fc.Advance(59 * time.Second)
+	select {
+	case <-done:
+		t.Fatal("wake up too early")
+	default:
+	}
+	fc.Advance(time.Second)
+	<-done
+
+	select {
+	case <-fc.After(0):
+	default:
+		t.Error("after 0 should fire at once")
+	}
+}
+
+func Test_TimerPause(t *testing.T) {
+	fc := NewFakeClock(time.Unix(0, 0))
+	tm := NewTimerWithClock(fc, 75)
+	tm.Start()
+	// not multiple of 10ms
+	if n := advance(fc, tm, 300*time.Millisecond); n != 4 {
+		t.Errorf("the timer should tick 4 times in 300ms, but it ticks %d times", n)
+	}
+
+	// 30ms left
+	advance(fc, tm, 45*time.Millisecond)
+	tm.Pause()
+	if n := advance(fc, tm, time.Second); n != 0 {
+		t.Errorf("the timer is paused, but it ticks %d times", n)
+	}
+	tm.Start()
+	if n := advance(fc, tm, 29*time.Millisecond); n != 0 {
+		t.Errorf("the time left should be kept across pause, but it ticks %d times", n)
+	}
+	if n := advance(fc, tm, time.Millisecond); n != 1 {
+		t.Errorf("the timer should tick after the time left, but it ticks %d times", n)
+	}
+}
+
+func Test_TimerSetInterval(t *testing.T) {
+	fc := NewFakeClock(time.Unix(0, 0))
+	tm := NewTimerWithClock(fc, 1000)
+	tm.Start()
+	advance(fc, tm, 500*time.Millisecond)
+	tm.SetInterval(600)
+	if n := advance(fc, tm, 99*time.Millisecond); n != 0 {
+		t.Errorf("the timer ticks %d times too early", n)
+	}
+	if n := advance(fc, tm, time.Millisecond); n != 1 {
+		t.Errorf("the timer should tick 600ms after the last tick, but it ticks %d times", n)
+	}
+	if n := advance(fc, tm, 1200*time.Millisecond); n != 2 {
+		t.Errorf("the timer should tick every 600ms, but it ticks %d times", n)
+	}
+
+	// shorter than the time passed, tick at once
+	advance(fc, tm, 500*time.Millisecond)
+	tm.SetInterval(100)
+	if n := advance(fc, tm, time.Millisecond); n != 1 {
+		t.Errorf("the timer should tick at once, but it ticks %d times", n)
+	}
+}
+
+func Test_TimerWaitContext(t *testing.T) {
+	fc := NewFakeClock(time.Unix(0, 0))
+	tm := NewTimerWithClock(fc, 100)
+	tm.Start()
+	fc.Advance(100 * time.Millisecond)
+	// the tick is kept though the timer is paused
+	tm.Pause()
+	if err := tm.WaitContext(context.Background()); err != nil {
+		t.Errorf("should get the tick: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- tm.WaitContext(ctx) }()
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("the wait should be cancelled, got %v", err)
+	}
+	select {
+	case <-tm.C():
+		t.Error("the timer is paused, there should be no tick")
+	default:
+	}
+}
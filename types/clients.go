@@ -1,9 +1,11 @@
 package types
 
 import (
-	"fmt"
+	"crypto/tls"
+	"net"
 	"sync"
 
+	"github.com/gogames/go_tetris/utils"
 	"github.com/hprose/hprose-go/hprose"
 )
 
@@ -11,11 +13,17 @@ import (
 type gameServerStub struct {
 	Start               func(tid int) error
 	Delete              func(tid int) error
-	Create              func(tid int) error
+	Create              func(tid int, settings map[string]int) error
 	SetNormalGameResult func(tid, winnerUid, bet int) error
 	SetTournamentResult func(tid, winnerUid int) error
 	SysText             func(text string) error
 	Deactivate          func() error
+	Kick                func(tid, uid int, ban bool) error
+	LockSeat            func(tid int, locked bool) error
+	MuteObservers       func(tid int, muted bool) error
+	TransferOwner       func(tid, uid int) error
+	SetSeries           func(tid int, series map[string]interface{}) error
+	RateLimitMetrics    func() (map[string]interface{}, error)
 }
 
 func newGameServerStub() *gameServerStub { return new(gameServerStub) }
@@ -51,15 +59,17 @@ type GameServersRpc struct {
 	gameServerStatus   map[string]bool
 	gsstatMu           sync.RWMutex
 	port               string
+	tls                *tls.Config // nil for plain http
 }
 
-func NewGameServerRpc(port string) *GameServersRpc {
+func NewGameServerRpc(port string, tlsConf *tls.Config) *GameServersRpc {
 	return &GameServersRpc{
 		gameServerClient:   make(map[string]hprose.Client),
 		gameServerStubs:    make(map[string]*gameServerStub),
 		gameServerNumConns: make(map[string]*numConn),
 		gameServerStatus:   make(map[string]bool),
 		port:               port,
+		tls:                tlsConf,
 	}
 }
 
@@ -86,8 +96,8 @@ func (gsr *GameServersRpc) IsServerExist(ip string) bool {
 	return ok
 }
 
-// add a new game server
-func (gsr *GameServersRpc) NewGameServer(ip string, maxConn int) {
+// add a new game server, the requests are signed by the filter
+func (gsr *GameServersRpc) NewGameServer(ip string, maxConn int, filter *utils.PrivFilter) {
 	addStub := func() {
 		gsr.gssMu.Lock()
 		defer gsr.gssMu.Unlock()
@@ -96,9 +106,8 @@ func (gsr *GameServersRpc) NewGameServer(ip string, maxConn int) {
 	addClient := func() {
 		gsr.gscMu.Lock()
 		defer gsr.gscMu.Unlock()
-		gsr.gameServerClient[ip] = hprose.NewHttpClient(fmt.Sprintf("http://%s:%s/", ip, gsr.port))
+		gsr.gameServerClient[ip] = utils.NewPrivClient(net.JoinHostPort(ip, gsr.port), gsr.tls, filter)
 		gsr.gameServerClient[ip].UseService(gsr.gameServerStubs[ip])
-		gsr.gameServerClient[ip].SetKeepAlive(true)
 	}
 	addNc := func() {
 		gsr.gsncMu.Lock()
@@ -196,6 +205,26 @@ func (gsr *GameServersRpc) GetStub(ip string) *gameServerStub {
 	return gsr.gameServerStubs[ip]
 }
 
+// the rate limit metrics of the game servers by ip, or the error of the server
+func (gsr *GameServersRpc) RateLimitMetrics() map[string]interface{} {
+	gsr.gssMu.RLock()
+	stubs := make(map[string]*gameServerStub, len(gsr.gameServerStubs))
+	for ip, stub := range gsr.gameServerStubs {
+		stubs[ip] = stub
+	}
+	gsr.gssMu.RUnlock()
+
+	res := make(map[string]interface{}, len(stubs))
+	for ip, stub := range stubs {
+		if metrics, err := stub.RateLimitMetrics(); err != nil {
+			res[ip] = err.Error()
+		} else {
+			res[ip] = metrics
+		}
+	}
+	return res
+}
+
 // deactivate all
 func (gsr *GameServersRpc) DeactivateAll() error {
 	gsr.gssMu.RLock()
@@ -0,0 +1,18 @@
+package types
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_RateLimitMetrics(t *testing.T) {
+	gsr := NewGameServerRpc("8080", nil)
+	metrics := map[string]interface{}{"buckets": 1}
+	gsr.gameServerStubs["10.0.0.1"] = &gameServerStub{RateLimitMetrics: func() (map[string]interface{}, error) { return metrics, nil }}
+	gsr.gameServerStubs["10.0.0.2"] = &gameServerStub{RateLimitMetrics: func() (map[string]interface{}, error) { return nil, errors.New("closed") }}
+	want := map[string]interface{}{"10.0.0.1": metrics, "10.0.0.2": "closed"}
+	if res := gsr.RateLimitMetrics(); !reflect.DeepEqual(res, want) {
+		t.Errorf("the metrics of each game server or its error, got %v", res)
+	}
+}
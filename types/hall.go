@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+
+	"github.com/gogames/go_tetris/errcode"
 )
 
 // normal hall
@@ -165,7 +167,7 @@ func (th *TournamentHall) newTable(id int) error {
 	return th.Tables.NewTable(id, th.getTitle(), th.host, 0)
 }
 
-var errCantAcceptMoreApplication = fmt.Errorf("不好意思, 报名人数已满, 请参加下期的争霸赛~")
+var errCantAcceptMoreApplication = errcode.New(errcode.CantAcceptMoreApplication)
 
 // apply
 func (th *TournamentHall) Apply(u *User) (int, error) {
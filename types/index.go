@@ -0,0 +1,192 @@
+package types
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/gogames/go_tetris/errcode"
+)
+
+// sort keys of the hall
+const (
+	SortId     = "id"     // table id, ascending by default
+	SortNewest = "newest" // creation order
+	SortBet    = "bet"
+	SortLevel  = "level" // average level of the players
+)
+
+var sortKeys = []string{SortId, SortNewest, SortBet, SortLevel}
+
+func isSortKey(key string) bool {
+	for _, k := range sortKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+var ErrIncorrectCursor = errcode.New(errcode.IncorrectCursor)
+
+// ordered index of the tables, a skip list for each sort key
+// the tables update their keys by Table.changed
+type tableIndex struct {
+	mu      sync.RWMutex
+	lists   map[string]*skipList
+	entries map[int]map[string]int // table id -> sort key -> value in the list
+	seq     int                    // creation order
+}
+
+func newTableIndex() *tableIndex {
+	idx := &tableIndex{
+		lists:   make(map[string]*skipList),
+		entries: make(map[int]map[string]int),
+	}
+	for _, key := range sortKeys {
+		idx.lists[key] = newSkipList()
+	}
+	return idx
+}
+
+func (idx *tableIndex) add(t *Table) {
+	vals := t.sortValues()
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if _, ok := idx.entries[t.tId]; ok {
+		return
+	}
+	idx.seq++
+	vals[SortNewest] = idx.seq
+	idx.insert(t.tId, vals)
+}
+
+// update the keys of the table, return false if the table is not indexed
+func (idx *tableIndex) update(t *Table) bool {
+	vals := t.sortValues()
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	old, ok := idx.entries[t.tId]
+	if !ok {
+		return false
+	}
+	vals[SortNewest] = old[SortNewest]
+	idx.delete(t.tId)
+	idx.insert(t.tId, vals)
+	return true
+}
+
+func (idx *tableIndex) remove(tid int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.delete(tid)
+}
+
+func (idx *tableIndex) insert(tid int, vals map[string]int) {
+	for key, l := range idx.lists {
+		l.insert(indexKey{vals[key], tid})
+	}
+	idx.entries[tid] = vals
+}
+
+func (idx *tableIndex) delete(tid int) {
+	vals, ok := idx.entries[tid]
+	if !ok {
+		return
+	}
+	for key, l := range idx.lists {
+		l.delete(indexKey{vals[key], tid})
+	}
+	delete(idx.entries, tid)
+}
+
+// scan the tables by the sort key from the cursor, until f returns false
+func (idx *tableIndex) scan(sortKey string, desc bool, cursor *indexKey, f func(key indexKey) bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	l, ok := idx.lists[sortKey]
+	if !ok {
+		l = idx.lists[SortId]
+	}
+	if desc {
+		for n := l.before(cursor); n != nil && f(n.key); n = n.prev {
+		}
+		return
+	}
+	for n := l.after(cursor); n != nil && f(n.key); n = n.next[0] {
+	}
+}
+
+func (idx *tableIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.entries)
+}
+
+// the values of the sort keys, except the creation order
+func (t *Table) sortValues() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return map[string]int{
+		SortId:    t.tId,
+		SortBet:   t.tBet,
+		SortLevel: t.avgLevel(),
+	}
+}
+
+// average level of the players * 100, 0 if no player
+func (t *Table) avgLevel() int {
+	sum, n := 0, 0
+	for _, u := range []*User{t._1p, t._2p} {
+		if u != nil {
+			sum += u.GetLevel()
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum * 100 / n
+}
+
+// the level of the owner, or the first player if the owner is not seated, -1 if no player
+func (t *Table) hostLevel() int {
+	for _, u := range []*User{t._1p, t._2p} {
+		if u != nil && u.GetUid() == t.moderation.owner {
+			return u.GetLevel()
+		}
+	}
+	for _, u := range []*User{t._1p, t._2p} {
+		if u != nil {
+			return u.GetLevel()
+		}
+	}
+	return -1
+}
+
+// inform the index, should be called without the lock of the table
+func (t *Table) changed() {
+	if t.onChange != nil {
+		t.onChange(t)
+	}
+}
+
+// reindex the table, e.g. the levels of the players change
+func (ts *Tables) Reindex(tid int) {
+	if t := ts.GetTableById(tid); t != nil {
+		ts.index.update(t)
+	}
+}
+
+// the cursor is the key of the last table of a page
+func (k indexKey) cursor() string { return fmt.Sprintf("%d:%d", k.val, k.tid) }
+
+func parseCursor(cursor string) (*indexKey, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	var k indexKey
+	if _, err := fmt.Sscanf(cursor, "%d:%d", &k.val, &k.tid); err != nil {
+		return nil, ErrIncorrectCursor
+	}
+	return &k, nil
+}
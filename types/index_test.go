@@ -0,0 +1,117 @@
+package types
+
+import "testing"
+
+func newUserWithLevel(uid, level int) *User {
+	u := NewUser(uid, "", "", "", "")
+	u.Level = level
+	return u
+}
+
+func Test_TableIndex(t *testing.T) {
+	ts := NewTables()
+	for i, bet := range []int{30, 10, 20, 10} {
+		ts.NewTable(i+1, "", "", bet)
+	}
+	ts.GetTableById(3).Join(newUserWithLevel(1, 5))
+	ts.GetTableById(4).Join(newUserWithLevel(2, 2))
+
+	ids := func(tables []map[string]interface{}) (res []int) {
+		for _, t := range tables {
+			res = append(res, t["table_id"].(int))
+		}
+		return
+	}
+	equal := func(a, b []int) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, c := range []struct {
+		q    TableQuery
+		want []int
+	}{
+		{TableQuery{}, []int{1, 2, 3, 4}},
+		{TableQuery{Sort: SortNewest, Desc: true}, []int{4, 3, 2, 1}},
+		{TableQuery{Sort: SortBet}, []int{2, 4, 3, 1}},
+		{TableQuery{Sort: SortLevel, Desc: true}, []int{3, 4, 2, 1}},
+		{TableQuery{MinBet: 15, MaxBet: 25}, []int{3}},
+		{TableQuery{MinHostLevel: 1, MaxHostLevel: 3}, []int{4}},
+	} {
+		res, _, err := ts.Search(c.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(res); !equal(got, c.want) {
+			t.Errorf("query %+v should get %v, got %v", c.q, c.want, got)
+		}
+	}
+
+	// the level changes when the player quits
+	ts.GetTableById(3).Quit(1)
+	res, _, _ := ts.Search(TableQuery{Sort: SortLevel, Desc: true, Limit: 1})
+	if got := ids(res); !equal(got, []int{4}) {
+		t.Errorf("table 4 should have the highest level, got %v", got)
+	}
+
+	// cursor
+	q := TableQuery{Sort: SortBet, Desc: true, Limit: 3}
+	res, cursor, _ := ts.Search(q)
+	if got := ids(res); !equal(got, []int{1, 3, 4}) || cursor == "" {
+		t.Fatalf("the first page should be 1, 3, 4 with a cursor, got %v, %q", got, cursor)
+	}
+	q.Cursor = cursor
+	res, cursor, _ = ts.Search(q)
+	if got := ids(res); !equal(got, []int{2}) || cursor != "" {
+		t.Errorf("the last page should be 2 without a cursor, got %v, %q", got, cursor)
+	}
+	if _, _, err := ts.Search(TableQuery{Cursor: "x"}); err != ErrIncorrectCursor {
+		t.Errorf("the cursor should be incorrect, got %v", err)
+	}
+
+	// deleted tables are not indexed
+	ts.DelTable(1)
+	ts.GetTableById(2).changed()
+	if ts.index.Len() != 3 {
+		t.Errorf("3 tables should be indexed, got %d", ts.index.Len())
+	}
+
+	// pages
+	if got := ids(ts.Wrap(2, 2, false)); !equal(got, []int{4}) {
+		t.Errorf("the second page should be 4, got %v", got)
+	}
+	if got := ids(ts.Wrap(2, 5, false)); !equal(got, []int{4}) {
+		t.Errorf("the page after the last should be the last page, got %v", got)
+	}
+}
+
+func Test_TableEvents(t *testing.T) {
+	ts := NewTables()
+	events := make([]string, 0)
+	ts.SetListener(func(event string, tid int, table *Table) {
+		events = append(events, event)
+	})
+	ts.NewTable(1, "", "", 0)
+	ts.JoinTable(1, NewUser(1, "", "", "", ""), false)
+	ts.GetTableById(1).SwitchReady(1)
+	table := ts.GetTableById(1)
+	ts.DelTable(1)
+	// the removed table does not inform
+	table.Quit(1)
+	want := []string{TableCreated, TableUpdated, TableUpdated, TableRemoved}
+	if len(events) != len(want) {
+		t.Fatalf("the events should be %v, got %v", want, events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("the events should be %v, got %v", want, events)
+		}
+	}
+}
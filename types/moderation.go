@@ -0,0 +1,168 @@
+package types
+
+import "github.com/gogames/go_tetris/errcode"
+
+var (
+	ErrBanned         = errcode.New(errcode.Banned)
+	ErrKickSelf       = errcode.New(errcode.KickSelf)
+	ErrKickInGame     = errcode.New(errcode.KickInGame)
+	ErrNotInTable     = errcode.New(errcode.NotInTable)
+	ErrObserversMuted = errcode.New(errcode.ObserversMuted)
+	ErrTransferToSelf = errcode.New(errcode.TransferToSelf)
+)
+
+// the owner of a table and what the owner decides
+type moderation struct {
+	owner      int          // uid of the owner, -1 if the table has no owner
+	banned     map[int]bool // uid of the users who can not join or observe
+	seatLocked bool         // no one can take the second seat
+	obsMuted   bool         // observers can not chat
+}
+
+func newModeration(owner int) moderation {
+	return moderation{owner: owner, banned: make(map[int]bool)}
+}
+
+// create a new Table owned by the user, the settings should be validated
+func (ts *Tables) NewOwnedTable(id int, title, host string, bet int, settings TableSettings, owner int) error {
+	return ts.addTable(newOwnedTable(id, title, host, bet, settings, owner))
+}
+
+func newOwnedTable(id int, title, host string, bet int, settings TableSettings, owner int) *Table {
+	t := newTable(id, title, host, bet, settings)
+	t.moderation.owner = owner
+	return t
+}
+
+func (t *Table) GetOwner() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.moderation.owner
+}
+
+func (t *Table) IsOwner(uid int) bool {
+	return uid >= 0 && t.GetOwner() == uid
+}
+
+// ban the user from joining or observing the table again
+// the caller should kick the user if the user is in the table
+func (t *Table) Ban(owner, uid int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.checkOwner(owner, uid); err != nil {
+		return err
+	}
+	t.moderation.banned[uid] = true
+	return nil
+}
+
+// check if the owner can kick the user out of the table
+// the players can not be kicked during a game, or the game is over without a result
+// nor between the games of a series with frozen bets, or the owner takes the bets by forfeit
+func (t *Table) CanKick(owner, uid int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.checkOwner(owner, uid); err != nil {
+		return err
+	}
+	if uid < 0 {
+		return ErrNotInTable
+	}
+	frozen := t.series.games > 0 && !t.series.over && t.settings.Settle == SettlePerSeries
+	if (t.tStat == statInGame || frozen) && (t._1p.GetUid() == uid || t._2p.GetUid() == uid) {
+		return ErrKickInGame
+	}
+	return nil
+}
+
+func (t *Table) checkOwner(owner, uid int) error {
+	switch {
+	case owner < 0 || owner != t.moderation.owner:
+		return ErrNotTableOwner
+	case uid == owner:
+		return ErrKickSelf
+	}
+	return nil
+}
+
+func (t *Table) IsBanned(uid int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.moderation.banned[uid]
+}
+
+// lock or unlock the second seat
+// if the seat is locked, the table is full once a player is seated
+func (t *Table) LockSeat(owner int, locked bool) error {
+	defer t.changed()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if owner < 0 || owner != t.moderation.owner {
+		return ErrNotTableOwner
+	}
+	t.moderation.seatLocked = locked
+	return nil
+}
+
+func (t *Table) IsSeatLocked() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.moderation.seatLocked
+}
+
+// mute or unmute the observers
+func (t *Table) MuteObservers(owner int, muted bool) error {
+	defer t.changed()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if owner < 0 || owner != t.moderation.owner {
+		return ErrNotTableOwner
+	}
+	t.moderation.obsMuted = muted
+	return nil
+}
+
+// for game server, the auth server has already checked the owner
+func (t *Table) SetObsMuted(muted bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.moderation.obsMuted = muted
+}
+
+func (t *Table) IsObsMuted() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.moderation.obsMuted
+}
+
+// hand the table to another user in the table
+func (t *Table) TransferOwner(owner, uid int) error {
+	defer t.changed()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case owner < 0 || owner != t.moderation.owner:
+		return ErrNotTableOwner
+	case uid == owner:
+		return ErrTransferToSelf
+	case uid < 0 || !(t._1p.GetUid() == uid || t._2p.GetUid() == uid || t.obs.IsUserExist(uid)):
+		return ErrNotInTable
+	}
+	t.moderation.owner = uid
+	return nil
+}
+
+// the owner quits, hand the table to the other player if there is one
+func (t *Table) handOverOnQuit(uid int) {
+	if uid != t.moderation.owner {
+		return
+	}
+	switch {
+	case t._1p != nil:
+		t.moderation.owner = t._1p.GetUid()
+	case t._2p != nil:
+		t.moderation.owner = t._2p.GetUid()
+	default:
+		t.moderation.owner = -1
+	}
+}
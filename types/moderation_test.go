@@ -0,0 +1,110 @@
+package types
+
+import "testing"
+
+func Test_Moderation(t *testing.T) {
+	ts := NewTables()
+	if err := ts.NewOwnedTable(1, "", "", 0, DefaultTableSettings(), 10); err != nil {
+		t.Fatal(err)
+	}
+	table := ts.GetTableById(1)
+	table.Join(NewUser(10, "", "", "owner", ""))
+	table.JoinOB(NewUser(11, "", "", "ob", ""))
+	if !table.IsOwner(10) || table.IsOwner(11) {
+		t.Fatal("the table should be owned by 10")
+	}
+
+	// kick and ban
+	if err := table.CanKick(11, 10); err != ErrNotTableOwner {
+		t.Errorf("only the owner can kick, got %v", err)
+	}
+	if err := table.CanKick(10, 10); err != ErrKickSelf {
+		t.Errorf("the owner can not kick itself, got %v", err)
+	}
+	if err := table.CanKick(10, 11); err != nil {
+		t.Errorf("the owner can kick the observer, got %v", err)
+	}
+	if err := table.Ban(10, 12); err != nil || !table.IsBanned(12) || table.IsBanned(11) {
+		t.Errorf("the owner can ban anyone, got %v", err)
+	}
+
+	// lock the seat
+	if err := table.LockSeat(11, true); err != ErrNotTableOwner {
+		t.Errorf("only the owner can lock the seat, got %v", err)
+	}
+	if err := table.LockSeat(10, true); err != nil {
+		t.Fatal(err)
+	}
+	if !table.IsFull() {
+		t.Error("the table should be full when the seat is locked")
+	}
+	if err := table.Join(NewUser(13, "", "", "", "")); err != ErrRoomFull {
+		t.Errorf("can not take the locked seat, got %v", err)
+	}
+	table.LockSeat(10, false)
+	if table.IsFull() {
+		t.Error("the seat is unlocked")
+	}
+
+	// mute the observers
+	if err := table.MuteObservers(10, true); err != nil || !table.IsObsMuted() {
+		t.Errorf("the owner can mute the observers, got %v", err)
+	}
+
+	// transfer
+	if err := table.TransferOwner(10, 13); err != ErrNotInTable {
+		t.Errorf("can not hand the table to someone not in it, got %v", err)
+	}
+	if err := table.TransferOwner(10, 11); err != nil || table.GetOwner() != 11 {
+		t.Fatalf("the owner should be 11, got %v", err)
+	}
+	if err := table.LockSeat(10, true); err != ErrNotTableOwner {
+		t.Errorf("the old owner has no rights, got %v", err)
+	}
+
+	// the owner quits, the player takes the table
+	table.Quit(11)
+	if table.GetOwner() != 10 {
+		t.Errorf("the player should take the table, got %d", table.GetOwner())
+	}
+	table.Quit(10)
+	if table.GetOwner() != -1 {
+		t.Errorf("the empty table should have no owner, got %d", table.GetOwner())
+	}
+}
+
+func Test_KickMidSeries(t *testing.T) {
+	ts := NewTables()
+	s := DefaultTableSettings()
+	s.Series, s.Settle = 2, SettlePerSeries
+	if err := ts.NewOwnedTable(1, "", "", 10, s, 1); err != nil {
+		t.Fatal(err)
+	}
+	table := ts.GetTableById(1)
+	table.Join(NewUser(1, "", "", "owner", ""))
+	table.Join(NewUser(2, "", "", "", ""))
+	table.JoinOB(NewUser(3, "", "", "ob", ""))
+
+	table.Start()
+	if err := table.CanKick(1, 2); err != ErrKickInGame {
+		t.Errorf("can not kick the player in game, got %v", err)
+	}
+	table.Stop()
+	table.RecordSeriesWin(1, false)
+
+	// between the games, the bets are still frozen
+	if err := table.CanKick(1, 2); err != ErrKickInGame {
+		t.Errorf("can not kick the player between the games of the series, got %v", err)
+	}
+	if err := table.CanKick(1, 3); err != nil {
+		t.Errorf("the observer can be kicked, got %v", err)
+	}
+
+	// the series is over, the bets are settled
+	table.Start()
+	table.Stop()
+	table.RecordSeriesWin(1, false)
+	if err := table.CanKick(1, 2); err != nil {
+		t.Errorf("the player can be kicked after the series, got %v", err)
+	}
+}
@@ -72,6 +72,13 @@ func (this *obs) GetAll() []int {
 	return us
 }
 
+// number of observers
+func (this *obs) Len() int {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return len(this.users)
+}
+
 // check if a user is in obs
 func (this *obs) IsUserExist(uid int) bool {
 	this.mu.RLock()
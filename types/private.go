@@ -0,0 +1,91 @@
+package types
+
+import (
+	"crypto/subtle"
+
+	"github.com/gogames/go_tetris/errcode"
+)
+
+var (
+	ErrPrivateTable  = errcode.New(errcode.PrivateTable)
+	ErrNotTableOwner = errcode.New(errcode.NotTableOwner)
+)
+
+// private table, only the owner and the users admitted by the password or an invite can join or observe
+type privacy struct {
+	private  bool
+	password string          // hash of the password, empty if the table can only be joined by invites
+	invites  map[string]bool // one-time invite tokens
+	allowed  map[int]bool    // uid of the admitted users
+}
+
+func newPrivacy(owner int, passwordHash string) privacy {
+	return privacy{
+		private:  true,
+		password: passwordHash,
+		invites:  make(map[string]bool),
+		allowed:  map[int]bool{owner: true},
+	}
+}
+
+// create a new private Table, the password should be hashed
+func (ts *Tables) NewPrivateTable(id int, title, host string, bet int, settings TableSettings, owner int, passwordHash string) error {
+	t := newOwnedTable(id, title, host, bet, settings, owner)
+	t.privacy = newPrivacy(owner, passwordHash)
+	return ts.addTable(t)
+}
+
+func (t *Table) IsPrivate() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.privacy.private
+}
+
+// rotate the password, the admitted users are still admitted
+func (t *Table) SetPassword(uid int, passwordHash string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.privacy.private || uid != t.moderation.owner {
+		return ErrNotTableOwner
+	}
+	t.privacy.password = passwordHash
+	return nil
+}
+
+// add a one-time invite token
+func (t *Table) AddInvite(uid int, token string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.privacy.private || uid != t.moderation.owner {
+		return ErrNotTableOwner
+	}
+	t.privacy.invites[token] = true
+	return nil
+}
+
+// admit the user by an invite token or the hash of the password
+// the invite token is used up once the user is admitted by it
+func (t *Table) Admit(uid int, invite, passwordHash string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := &t.privacy
+	switch {
+	case !p.private, p.allowed[uid]:
+	case invite != "" && p.invites[invite]:
+		delete(p.invites, invite)
+		p.allowed[uid] = true
+	case passwordHash != "" && p.password != "" &&
+		subtle.ConstantTimeCompare([]byte(passwordHash), []byte(p.password)) == 1:
+		p.allowed[uid] = true
+	default:
+		return ErrPrivateTable
+	}
+	return nil
+}
+
+// check if the user is admitted
+func (t *Table) IsAllowed(uid int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.privacy.private || t.privacy.allowed[uid]
+}
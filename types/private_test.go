@@ -0,0 +1,59 @@
+package types
+
+import "testing"
+
+func Test_PrivateTable(t *testing.T) {
+	ts := NewTables()
+	if err := ts.NewPrivateTable(1, "", "", 0, DefaultTableSettings(), 10, "hash"); err != nil {
+		t.Fatal(err)
+	}
+	table := ts.GetTableById(1)
+	if !table.IsPrivate() || table.GetOwner() != 10 {
+		t.Fatal("the table should be private and owned by 10")
+	}
+	if !table.IsAllowed(10) {
+		t.Error("the owner should be allowed")
+	}
+
+	// password
+	if err := table.Admit(11, "", "wrong"); err != ErrPrivateTable {
+		t.Errorf("the wrong password should not be admitted, got %v", err)
+	}
+	if err := table.Admit(11, "", "hash"); err != nil || !table.IsAllowed(11) {
+		t.Errorf("the password should be admitted, got %v", err)
+	}
+
+	// rotate
+	if err := table.SetPassword(11, "other"); err != ErrNotTableOwner {
+		t.Errorf("only the owner can rotate the password, got %v", err)
+	}
+	if err := table.SetPassword(10, "new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Admit(12, "", "hash"); err != ErrPrivateTable {
+		t.Errorf("the old password should not be admitted, got %v", err)
+	}
+	if !table.IsAllowed(11) {
+		t.Error("the admitted user should still be allowed")
+	}
+
+	// one-time invite
+	if err := table.AddInvite(10, "invite"); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Admit(12, "invite", ""); err != nil {
+		t.Errorf("the invite should be admitted, got %v", err)
+	}
+	if err := table.Admit(13, "invite", ""); err != ErrPrivateTable {
+		t.Errorf("the invite is used up, got %v", err)
+	}
+
+	// public table
+	ts.NewTable(2, "", "", 0)
+	if err := ts.GetTableById(2).Admit(13, "", ""); err != nil {
+		t.Errorf("everyone can join a public table, got %v", err)
+	}
+	if err := ts.GetTableById(2).AddInvite(13, "invite"); err != ErrNotTableOwner {
+		t.Errorf("can not invite to a public table, got %v", err)
+	}
+}
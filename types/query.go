@@ -0,0 +1,237 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gogames/go_tetris/errcode"
+)
+
+const maxTablesInPage = 50
+
+// visibility of the tables to search
+const (
+	VisibilityAny     = ""
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
+// query of the hall, the zero value matches all the tables
+type TableQuery struct {
+	Sort   string // SortId by default
+	Desc   bool
+	Cursor string // the cursor of the last page, empty for the first page
+	Limit  int    // defaultTableInPage by default
+
+	// filters, the max 0 means no limit
+	WaitingOnly                bool
+	MinBet, MaxBet             int
+	MinHostLevel, MaxHostLevel int
+	FreeSeat                   bool
+	Preset                     string // name in SettingPresets or PresetCustom, empty for any
+	Title                      string // substring of the title, case insensitive
+	Owner                      string // nickname of the owner
+	MinLevel, MaxLevel         int    // level of a seated player, the opponent of the one who joins
+	MinObs, MaxObs             int    // number of the observers
+	Visibility                 string
+}
+
+// keys of the query for hprose
+const (
+	QuerySort         = "sort"
+	QueryDesc         = "desc"
+	QueryCursor       = "cursor"
+	QueryLimit        = "limit"
+	QueryWaitingOnly  = "waiting"
+	QueryMinBet       = "bet_min"
+	QueryMaxBet       = "bet_max"
+	QueryMinHostLevel = "host_level_min"
+	QueryMaxHostLevel = "host_level_max"
+	QueryFreeSeat     = "free_seat"
+	QueryPreset       = "preset"
+	QueryTitle        = "title"
+	QueryOwner        = "owner"
+	QueryMinLevel     = "level_min"
+	QueryMaxLevel     = "level_max"
+	QueryMinObs       = "obs_min"
+	QueryMaxObs       = "obs_max"
+	QueryVisibility   = "visibility"
+)
+
+// parse the query from hprose, the values can be strings, e.g. from a form
+func ParseTableQuery(m map[string]interface{}) (q TableQuery, err error) {
+	ints := map[string]*int{
+		QueryLimit:        &q.Limit,
+		QueryMinBet:       &q.MinBet,
+		QueryMaxBet:       &q.MaxBet,
+		QueryMinHostLevel: &q.MinHostLevel,
+		QueryMaxHostLevel: &q.MaxHostLevel,
+		QueryMinLevel:     &q.MinLevel,
+		QueryMaxLevel:     &q.MaxLevel,
+		QueryMinObs:       &q.MinObs,
+		QueryMaxObs:       &q.MaxObs,
+	}
+	strs := map[string]*string{
+		QuerySort:       &q.Sort,
+		QueryCursor:     &q.Cursor,
+		QueryPreset:     &q.Preset,
+		QueryTitle:      &q.Title,
+		QueryOwner:      &q.Owner,
+		QueryVisibility: &q.Visibility,
+	}
+	bools := map[string]*bool{
+		QueryDesc:        &q.Desc,
+		QueryWaitingOnly: &q.WaitingOnly,
+		QueryFreeSeat:    &q.FreeSeat,
+	}
+	for key, val := range m {
+		var ok bool
+		switch {
+		case ints[key] != nil:
+			*ints[key], ok = toInt(val)
+		case strs[key] != nil:
+			*strs[key], ok = val.(string)
+		case bools[key] != nil:
+			*bools[key], ok = toBool(val)
+		default:
+			return q, errcode.New(errcode.UnknownQuery, key)
+		}
+		if !ok {
+			return q, errcode.New(errcode.IncorrectQuery, key, val)
+		}
+	}
+	return q, q.validate()
+}
+
+func (q TableQuery) validate() error {
+	if q.Sort != "" && !isSortKey(q.Sort) {
+		return errcode.New(errcode.UnknownSort, q.Sort)
+	}
+	if q.Preset != "" && q.Preset != PresetCustom {
+		if _, ok := SettingPresets[q.Preset]; !ok {
+			return errcode.New(errcode.UnknownPreset, q.Preset)
+		}
+	}
+	switch q.Visibility {
+	case VisibilityAny, VisibilityPublic, VisibilityPrivate:
+	default:
+		return errcode.New(errcode.UnknownVisibility, q.Visibility)
+	}
+	return nil
+}
+
+func toInt(val interface{}) (int, bool) {
+	switch v := val.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), v == float64(int(v))
+	case string:
+		i, err := strconv.Atoi(v)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func toBool(val interface{}) (bool, bool) {
+	switch v := val.(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(v)
+		return b, err == nil
+	}
+	return false, false
+}
+
+// check if the value is in [min, max], max 0 means no limit
+func inRange(val, min, max int) bool {
+	return val >= min && (max <= 0 || val <= max)
+}
+
+// check if the table matches the filters
+func (q TableQuery) match(t *Table) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case q.WaitingOnly && t.tStat == statInGame,
+		!inRange(t.tBet, q.MinBet, q.MaxBet),
+		q.FreeSeat && t.isFull(),
+		q.Preset != "" && t.settings.Preset() != q.Preset,
+		q.Visibility == VisibilityPublic && t.privacy.private,
+		q.Visibility == VisibilityPrivate && !t.privacy.private,
+		q.Title != "" && !strings.Contains(strings.ToLower(t.tTitle), strings.ToLower(q.Title)),
+		!inRange(t.obs.Len(), q.MinObs, q.MaxObs):
+		return false
+	}
+	if (q.MinHostLevel > 0 || q.MaxHostLevel > 0) && !inRange(t.hostLevel(), q.MinHostLevel, q.MaxHostLevel) {
+		return false
+	}
+	if q.MinLevel > 0 || q.MaxLevel > 0 {
+		found := false
+		for _, u := range []*User{t._1p, t._2p} {
+			if u != nil && inRange(u.GetLevel(), q.MinLevel, q.MaxLevel) {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.Owner != "" {
+		if u := t.owner(); u == nil || u.Nickname != q.Owner {
+			return false
+		}
+	}
+	return true
+}
+
+// the owner if the owner is in the table
+func (t *Table) owner() *User {
+	switch uid := t.moderation.owner; {
+	case uid < 0:
+		return nil
+	case t._1p.GetUid() == uid:
+		return t._1p
+	case t._2p.GetUid() == uid:
+		return t._2p
+	default:
+		return t.obs.GetUserById(uid)
+	}
+}
+
+// search the tables, return a page and the cursor of the next page
+// the cursor is empty if there is no more table
+func (ts *Tables) Search(q TableQuery) ([]map[string]interface{}, string, error) {
+	after, err := parseCursor(q.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultTableInPage
+	}
+	if limit > maxTablesInPage {
+		limit = maxTablesInPage
+	}
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	res, next := make([]map[string]interface{}, 0, limit), ""
+	ts.index.scan(q.Sort, q.Desc, after, func(key indexKey) bool {
+		t := ts.Tables[key.tid]
+		if t == nil || !q.match(t) {
+			return true
+		}
+		if len(res) == limit {
+			next = after.cursor()
+			return false
+		}
+		res = append(res, t.WrapTable())
+		k := key
+		after = &k
+		return true
+	})
+	return res, next, nil
+}
@@ -0,0 +1,74 @@
+package types
+
+import "testing"
+
+func Test_ParseTableQuery(t *testing.T) {
+	q, err := ParseTableQuery(map[string]interface{}{
+		QuerySort:       SortBet,
+		QueryDesc:       "true",
+		QueryMinBet:     "10",
+		QueryMaxLevel:   3,
+		QueryVisibility: VisibilityPrivate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Sort != SortBet || !q.Desc || q.MinBet != 10 || q.MaxLevel != 3 || q.Visibility != VisibilityPrivate {
+		t.Errorf("unexpected query %+v", q)
+	}
+	for _, m := range []map[string]interface{}{
+		{"color": "red"},
+		{QuerySort: "name"},
+		{QueryMinBet: "ten"},
+		{QueryPreset: "unknown"},
+		{QueryVisibility: "secret"},
+	} {
+		if _, err := ParseTableQuery(m); err == nil {
+			t.Errorf("the query %v should be invalid", m)
+		}
+	}
+}
+
+func Test_SearchFilters(t *testing.T) {
+	ts := NewTables()
+	blitz := SettingPresets["blitz"]
+	blitz.Series, blitz.Settle = 1, SettlePerGame
+	ts.NewOwnedTable(1, "Fast Game", "", 0, blitz, 1)
+	ts.NewPrivateTable(2, "slow game", "", 0, DefaultTableSettings(), 2, "")
+	ts.NewTable(3, "casual", "", 0)
+	ts.GetTableById(1).Join(newUserWithLevel(1, 4))
+	ts.GetTableById(2).Join(NewUser(2, "", "", "bob", ""))
+	ts.GetTableById(2).JoinOB(NewUser(3, "", "", "", ""))
+
+	for _, c := range []struct {
+		q    TableQuery
+		want []int
+	}{
+		{TableQuery{Title: "GAME"}, []int{1, 2}},
+		{TableQuery{Preset: "blitz"}, []int{1}},
+		{TableQuery{Preset: "classic"}, []int{2, 3}},
+		{TableQuery{Visibility: VisibilityPublic}, []int{1, 3}},
+		{TableQuery{Owner: "bob"}, []int{2}},
+		{TableQuery{MinLevel: 2}, []int{1}},
+		{TableQuery{MinObs: 1}, []int{2}},
+	} {
+		res, _, err := ts.Search(c.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]int, 0)
+		for _, m := range res {
+			got = append(got, m["table_id"].(int))
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("query %+v should get %v, got %v", c.q, c.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("query %+v should get %v, got %v", c.q, c.want, got)
+				break
+			}
+		}
+	}
+}
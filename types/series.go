@@ -0,0 +1,73 @@
+package types
+
+// the description of the series message to the clients
+const DescSeries = "series"
+
+// how the bets of a series are settled
+const (
+	SettlePerGame   = iota // every game is settled, as a single game
+	SettlePerSeries        // the bets are frozen once, and the winner of the series takes them
+)
+
+// a series of games between the two players, the first to win Settings.Series games wins the series
+type series struct {
+	wins1p, wins2p int
+	games          int  // games played in the series
+	over           bool // a new series starts with the next game
+}
+
+// the series is in progress, a new game continues it
+func (t *Table) IsMidSeries() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.series.games > 0 && !t.series.over
+}
+
+// record the winner of a game, return true if the series is over
+// forfeit ends the series, e.g. the loser quits
+func (t *Table) RecordSeriesWin(winner int, forfeit bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := &t.series
+	s.games++
+	switch winner {
+	case t._1p.GetUid():
+		s.wins1p++
+	case t._2p.GetUid():
+		s.wins2p++
+	}
+	s.over = forfeit || s.wins1p >= t.settings.Series || s.wins2p >= t.settings.Series
+	return s.over
+}
+
+// a new opponent, a new series
+func (t *Table) resetSeries() {
+	t.series = series{}
+}
+
+// for hprose and the series message
+func (t *Table) WrapSeries() map[string]interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.wrapSeries()
+}
+
+func (t *Table) wrapSeries() map[string]interface{} {
+	return map[string]interface{}{
+		"wins":   t.settings.Series,
+		"settle": t.settings.Settle,
+		"1p":     t.series.wins1p,
+		"2p":     t.series.wins2p,
+		"games":  t.series.games,
+		"over":   t.series.over,
+		// the game is over, the players should ready for the next one
+		"rematch": t.series.games > 0 && t.tStat != statInGame,
+	}
+}
+
+// the bets of the series are frozen when the first game starts, the next game does not freeze them again
+func (t *Table) IsSeriesBetFrozen() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.series.games > 0 && !t.series.over && t.settings.Settle == SettlePerSeries
+}
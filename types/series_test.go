@@ -0,0 +1,57 @@
+package types
+
+import "testing"
+
+func Test_Series(t *testing.T) {
+	ts := NewTables()
+	s := DefaultTableSettings()
+	s.Series, s.Settle = 2, SettlePerSeries
+	if err := ts.NewTableWithSettings(1, "", "", 10, s); err != nil {
+		t.Fatal(err)
+	}
+	table := ts.GetTableById(1)
+	table.Join(NewUser(1, "", "", "", ""))
+	table.Join(NewUser(2, "", "", "", ""))
+	if table.IsMidSeries() || table.IsSeriesBetFrozen() {
+		t.Fatal("the series should not start yet")
+	}
+
+	table.Start()
+	table.Stop()
+	if table.RecordSeriesWin(1, false) {
+		t.Fatal("the series should not be over after 1 win")
+	}
+	if !table.IsMidSeries() || !table.IsSeriesBetFrozen() {
+		t.Error("the series is in progress, the bets are frozen")
+	}
+	if m := table.WrapSeries(); m["1p"] != 1 || m["2p"] != 0 || m["rematch"] != true {
+		t.Errorf("unexpected series %v", m)
+	}
+
+	table.Start()
+	if m := table.WrapSeries(); m["games"] != 1 || m["rematch"] != false {
+		t.Errorf("the series should continue, got %v", m)
+	}
+	table.Stop()
+	table.RecordSeriesWin(2, false)
+	if !table.RecordSeriesWin(1, false) || table.IsMidSeries() {
+		t.Fatal("the series should be over after 2 wins")
+	}
+
+	// a new series starts with the next game
+	table.Start()
+	if m := table.WrapSeries(); m["games"] != 0 || m["1p"] != 0 {
+		t.Errorf("a new series should start, got %v", m)
+	}
+	table.Stop()
+
+	// the opponent quits, the series is forfeited
+	table.RecordSeriesWin(1, false)
+	table.Quit(2)
+	if table.IsMidSeries() {
+		t.Error("the series should be reset when a player quits")
+	}
+	if !table.RecordSeriesWin(1, true) {
+		t.Error("the forfeited series should be over")
+	}
+}
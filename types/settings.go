@@ -0,0 +1,137 @@
+package types
+
+import "github.com/gogames/go_tetris/errcode"
+
+// keys of the settings for hprose
+const (
+	SettingHeight    = "height"
+	SettingWidth     = "width"
+	SettingNextCount = "next"
+	SettingInterval  = "interval"
+	SettingDuration  = "duration"
+	SettingKOLimit   = "ko"
+	SettingSeries    = "series"
+	SettingSettle    = "settle"
+)
+
+// settings of a table, chosen by the creator
+type TableSettings struct {
+	Height, Width int // size of the zone
+	NextCount     int // number of the next pieces to preview
+	Interval      int // falling interval in ms
+	Duration      int // game duration in seconds
+	KOLimit       int // the game is over when a player is ko so many times
+	Series        int // the series is over when a player wins so many games, 1 for a single game
+	Settle        int // SettlePerGame or SettlePerSeries
+}
+
+func DefaultTableSettings() TableSettings {
+	return TableSettings{
+		Height:    zoneHeight,
+		Width:     zoneWidth,
+		NextCount: defaultNumOfNextPiece,
+		Interval:  defaultInterval,
+		Duration:  defaultDuration,
+		KOLimit:   defaultKOLimit,
+		Series:    1,
+		Settle:    SettlePerGame,
+	}
+}
+
+// rule presets, the series and the settlement are not rules
+const PresetCustom = "custom"
+
+var SettingPresets = map[string]TableSettings{
+	"classic":  DefaultTableSettings(),
+	"blitz":    {Height: zoneHeight, Width: zoneWidth, NextCount: 3, Interval: 500, Duration: 60, KOLimit: 3},
+	"marathon": {Height: zoneHeight, Width: zoneWidth, NextCount: defaultNumOfNextPiece, Interval: defaultInterval, Duration: 600, KOLimit: 10},
+	"wide":     {Height: zoneHeight, Width: 16, NextCount: defaultNumOfNextPiece, Interval: defaultInterval, Duration: defaultDuration, KOLimit: defaultKOLimit},
+}
+
+// the name of the preset with the same rules, PresetCustom if none
+func (s TableSettings) Preset() string {
+	for name, p := range SettingPresets {
+		if s.Height == p.Height && s.Width == p.Width && s.NextCount == p.NextCount &&
+			s.Interval == p.Interval && s.Duration == p.Duration && s.KOLimit == p.KOLimit {
+			return name
+		}
+	}
+	return PresetCustom
+}
+
+var settingKeys = []string{SettingHeight, SettingWidth, SettingNextCount, SettingInterval, SettingDuration, SettingKOLimit, SettingSeries, SettingSettle}
+
+// the range of each setting
+var settingRanges = map[string][2]int{
+	SettingHeight:    {16, 30},
+	SettingWidth:     {8, 16},
+	SettingNextCount: {1, 6},
+	SettingInterval:  {200, 2000},
+	SettingDuration:  {60, 600},
+	SettingKOLimit:   {1, 10},
+	SettingSeries:    {1, 5},
+	SettingSettle:    {SettlePerGame, SettlePerSeries},
+}
+
+var settingNames = map[string]string{
+	SettingHeight:    "棋盘高度",
+	SettingWidth:     "棋盘宽度",
+	SettingNextCount: "预览方块数",
+	SettingInterval:  "下落间隔(毫秒)",
+	SettingDuration:  "游戏时长(秒)",
+	SettingKOLimit:   "KO次数",
+	SettingSeries:    "系列赛胜场数",
+	SettingSettle:    "结算方式",
+}
+
+func (s TableSettings) Validate() error {
+	m := s.Wrap()
+	for _, key := range settingKeys {
+		if r, val := settingRanges[key], m[key]; val < r[0] || val > r[1] {
+			return errcode.New(errcode.SettingOutOfRange, settingNames[key], r[0], r[1])
+		}
+	}
+	return nil
+}
+
+// for hprose
+func (s TableSettings) Wrap() map[string]int {
+	return map[string]int{
+		SettingHeight:    s.Height,
+		SettingWidth:     s.Width,
+		SettingNextCount: s.NextCount,
+		SettingInterval:  s.Interval,
+		SettingDuration:  s.Duration,
+		SettingKOLimit:   s.KOLimit,
+		SettingSeries:    s.Series,
+		SettingSettle:    s.Settle,
+	}
+}
+
+// parse the settings from hprose, the missing ones are default
+func ParseTableSettings(m map[string]int) (TableSettings, error) {
+	s := DefaultTableSettings()
+	for key, val := range m {
+		switch key {
+		case SettingHeight:
+			s.Height = val
+		case SettingWidth:
+			s.Width = val
+		case SettingNextCount:
+			s.NextCount = val
+		case SettingInterval:
+			s.Interval = val
+		case SettingDuration:
+			s.Duration = val
+		case SettingKOLimit:
+			s.KOLimit = val
+		case SettingSeries:
+			s.Series = val
+		case SettingSettle:
+			s.Settle = val
+		default:
+			return s, errcode.New(errcode.UnknownSetting, key)
+		}
+	}
+	return s, s.Validate()
+}
@@ -0,0 +1,35 @@
+package types
+
+import "testing"
+
+func Test_TableSettings(t *testing.T) {
+	s, err := ParseTableSettings(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != DefaultTableSettings() {
+		t.Errorf("the settings should be default, got %+v", s)
+	}
+
+	s, err = ParseTableSettings(map[string]int{SettingWidth: 12, SettingKOLimit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Width != 12 || s.KOLimit != 3 || s.Height != zoneHeight {
+		t.Errorf("unexpected settings %+v", s)
+	}
+	if back, err := ParseTableSettings(s.Wrap()); err != nil || back != s {
+		t.Errorf("the wrapped settings should be parsed back, got %+v, %v", back, err)
+	}
+
+	for _, m := range []map[string]int{
+		{SettingWidth: 4},
+		{SettingDuration: 6000},
+		{SettingNextCount: 0},
+		{"speed": 1},
+	} {
+		if _, err := ParseTableSettings(m); err == nil {
+			t.Errorf("the settings %v should be invalid", m)
+		}
+	}
+}
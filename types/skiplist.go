@@ -0,0 +1,134 @@
+package types
+
+import (
+	"math/rand"
+	"time"
+)
+
+const maxSkipLevel = 16
+
+// the key of a table in a skip list, ordered by val then by table id
+type indexKey struct{ val, tid int }
+
+func (a indexKey) less(b indexKey) bool {
+	return a.val < b.val || a.val == b.val && a.tid < b.tid
+}
+
+type skipNode struct {
+	key  indexKey
+	next []*skipNode
+	prev *skipNode // on the bottom level, nil for the first node
+}
+
+// skip list of the table keys, O(log n) to insert, delete and seek
+// not thread safe, see tableIndex
+type skipList struct {
+	head   *skipNode
+	tail   *skipNode
+	level  int
+	length int
+	rnd    *rand.Rand
+}
+
+func newSkipList() *skipList {
+	return &skipList{
+		head:  &skipNode{next: make([]*skipNode, maxSkipLevel)},
+		level: 1,
+		rnd:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// a node is on the next level by the chance of 1/4
+func (sl *skipList) randomLevel() int {
+	l := 1
+	for l < maxSkipLevel && sl.rnd.Intn(4) == 0 {
+		l++
+	}
+	return l
+}
+
+// the last node less than the key on each level
+func (sl *skipList) path(key indexKey) (path [maxSkipLevel]*skipNode) {
+	x := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && x.next[i].key.less(key) {
+			x = x.next[i]
+		}
+		path[i] = x
+	}
+	return
+}
+
+func (sl *skipList) insert(key indexKey) {
+	path := sl.path(key)
+	if n := path[0].next[0]; n != nil && n.key == key {
+		return
+	}
+	lvl := sl.randomLevel()
+	for i := sl.level; i < lvl; i++ {
+		path[i] = sl.head
+	}
+	if lvl > sl.level {
+		sl.level = lvl
+	}
+	n := &skipNode{key: key, next: make([]*skipNode, lvl)}
+	for i := 0; i < lvl; i++ {
+		n.next[i] = path[i].next[i]
+		path[i].next[i] = n
+	}
+	if path[0] != sl.head {
+		n.prev = path[0]
+	}
+	if n.next[0] != nil {
+		n.next[0].prev = n
+	} else {
+		sl.tail = n
+	}
+	sl.length++
+}
+
+func (sl *skipList) delete(key indexKey) bool {
+	path := sl.path(key)
+	n := path[0].next[0]
+	if n == nil || n.key != key {
+		return false
+	}
+	for i := range n.next {
+		path[i].next[i] = n.next[i]
+	}
+	if n.next[0] != nil {
+		n.next[0].prev = n.prev
+	} else {
+		sl.tail = n.prev
+	}
+	for sl.level > 1 && sl.head.next[sl.level-1] == nil {
+		sl.level--
+	}
+	sl.length--
+	return true
+}
+
+// the first node after the key, the first node if key is nil
+func (sl *skipList) after(key *indexKey) *skipNode {
+	if key == nil {
+		return sl.head.next[0]
+	}
+	n := sl.path(*key)[0].next[0]
+	if n != nil && n.key == *key {
+		n = n.next[0]
+	}
+	return n
+}
+
+// the last node before the key, the last node if key is nil
+func (sl *skipList) before(key *indexKey) *skipNode {
+	if key == nil {
+		return sl.tail
+	}
+	if n := sl.path(*key)[0]; n != sl.head {
+		return n
+	}
+	return nil
+}
+
+func (sl *skipList) Len() int { return sl.length }
@@ -0,0 +1,56 @@
+package types
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// the skip list should be the same as a sorted slice
+func Test_SkipList(t *testing.T) {
+	sl := newSkipList()
+	keys := make(map[indexKey]bool)
+	for i := 0; i < 2000; i++ {
+		k := indexKey{rand.Intn(50), rand.Intn(100)}
+		if rand.Intn(3) == 0 {
+			if sl.delete(k) != keys[k] {
+				t.Fatalf("delete %v, exist %v", k, keys[k])
+			}
+			delete(keys, k)
+			continue
+		}
+		sl.insert(k)
+		keys[k] = true
+	}
+	want := make([]indexKey, 0, len(keys))
+	for k := range keys {
+		want = append(want, k)
+	}
+	sort.Slice(want, func(i, j int) bool { return want[i].less(want[j]) })
+	if sl.Len() != len(want) {
+		t.Fatalf("length should be %d, got %d", len(want), sl.Len())
+	}
+
+	i := 0
+	for n := sl.after(nil); n != nil; n = n.next[0] {
+		if n.key != want[i] {
+			t.Fatalf("the %dth key should be %v, got %v", i, want[i], n.key)
+		}
+		i++
+	}
+	for n := sl.before(nil); n != nil; n = n.prev {
+		i--
+		if n.key != want[i] {
+			t.Fatalf("backward, the %dth key should be %v, got %v", i, want[i], n.key)
+		}
+	}
+
+	// seek from a key
+	mid := want[len(want)/2]
+	if n := sl.after(&mid); n == nil || n.key != want[len(want)/2+1] {
+		t.Errorf("the key after %v should be %v", mid, want[len(want)/2+1])
+	}
+	if n := sl.before(&mid); n == nil || n.key != want[len(want)/2-1] {
+		t.Errorf("the key before %v should be %v", mid, want[len(want)/2-1])
+	}
+}
@@ -0,0 +1,33 @@
+package types
+
+// events of the tables for the hall subscription
+const (
+	TableCreated = "table_created"
+	TableUpdated = "table_updated"
+	TableRemoved = "table_removed"
+)
+
+// listen to the events of the tables, t is nil if the table is removed
+// f is called without the lock of the tables, but may be called inside a method of Tables,
+// so it should not call the methods of Tables
+func (ts *Tables) SetListener(f func(event string, tid int, t *Table)) {
+	ts.listenerMu.Lock()
+	defer ts.listenerMu.Unlock()
+	ts.listener = f
+}
+
+func (ts *Tables) emit(event string, tid int, t *Table) {
+	ts.listenerMu.RLock()
+	f := ts.listener
+	ts.listenerMu.RUnlock()
+	if f != nil {
+		f(event, tid, t)
+	}
+}
+
+// the table changes, update the index and inform the listener
+func (ts *Tables) tableChanged(t *Table) {
+	if ts.index.update(t) {
+		ts.emit(TableUpdated, t.tId, t)
+	}
+}
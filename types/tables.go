@@ -1,132 +1,39 @@
 package types
 
 import (
-	"container/list"
+	"context"
 	"encoding/json"
 	"fmt"
-	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/gogames/go_tetris/errcode"
 	"github.com/gogames/go_tetris/tetris"
 	"github.com/gogames/go_tetris/timer"
 )
 
 var (
 	ErrExisted  = fmt.Errorf("the table is already exist")
-	ErrNotExist = fmt.Errorf("找不到该桌子.")
-	ErrRoomFull = fmt.Errorf("桌子已满, 无法加入游戏.")
+	ErrNotExist = errcode.New(errcode.TableNotFound)
+	ErrRoomFull = errcode.New(errcode.RoomFull)
 )
 
-type sortedList struct {
-	l       *list.List
-	mu      sync.RWMutex
-	tableId []int
-}
-
-func newSortList() *sortedList {
-	return &sortedList{
-		l:       list.New(),
-		tableId: make([]int, 0),
-	}
-}
-
-func (sl *sortedList) GetAll() []int {
-	sl.mu.RLock()
-	defer sl.mu.RUnlock()
-	return sl.tableId
-}
-
-func (sl *sortedList) Get(index int) int {
-	if index < 0 {
-		return -1
-	}
-	sl.mu.RLock()
-	defer sl.mu.RUnlock()
-	for e := sl.l.Front(); e != nil; e = e.Next() {
-		if index == 0 {
-			return e.Value.(int)
-		}
-		index--
-	}
-	return -1
-}
-
-func (sl *sortedList) Set(index, val int) {
-	if index < 0 {
-		return
-	}
-	sl.mu.Lock()
-	defer sl.mu.Unlock()
-	for e := sl.l.Front(); e != nil; e = e.Next() {
-		if index == 0 {
-			e.Value = val
-		}
-		index--
-	}
-}
-
-func (sl *sortedList) Len() int {
-	sl.mu.RLock()
-	defer sl.mu.RUnlock()
-	return sl.l.Len()
-}
-
-func (sl *sortedList) Less(i, j int) bool { return sl.Get(i) < sl.Get(j) }
-
-func (sl *sortedList) Swap(i, j int) {
-	iVal, jVal := sl.Get(i), sl.Get(j)
-	sl.Set(i, jVal)
-	sl.Set(j, iVal)
-}
-
-// add new table id
-func (sl *sortedList) Add(i int) {
-	defer func() {
-		sl.Sort()
-	}()
-	sl.mu.Lock()
-	defer sl.mu.Unlock()
-	sl.l.PushFront(i)
-}
-
-func (sl *sortedList) Delete(val int) {
-	defer func() {
-		sl.Sort()
-	}()
-	sl.mu.Lock()
-	defer sl.mu.Unlock()
-	for e := sl.l.Front(); e != nil; e = e.Next() {
-		if e.Value.(int) == val {
-			sl.l.Remove(e)
-			return
-		}
-	}
-}
-
-func (sl *sortedList) Sort() {
-	sort.Sort(sl)
-	sl.mu.Lock()
-	defer sl.mu.Unlock()
-	sl.tableId = make([]int, 0)
-	for e := sl.l.Front(); e != nil; e = e.Next() {
-		sl.tableId = append(sl.tableId, e.Value.(int))
-	}
-}
-
 type Tables struct {
-	Tables        map[int]*Table
-	sortedTableId *sortedList
-	mu            sync.RWMutex
-	expires       map[int]*Table
+	Tables  map[int]*Table
+	index   *tableIndex
+	mu      sync.RWMutex
+	expires map[int]*Table
+	// hall subscription, see SetListener
+	listener   func(event string, tid int, t *Table)
+	listenerMu sync.RWMutex
 }
 
 func NewTables() *Tables {
 	ts := &Tables{
-		sortedTableId: newSortList(),
-		Tables:        make(map[int]*Table),
-		expires:       make(map[int]*Table),
+		index:   newTableIndex(),
+		Tables:  make(map[int]*Table),
+		expires: make(map[int]*Table),
 	}
 	return ts.init()
 }
@@ -169,56 +76,48 @@ func (ts *Tables) GetExpireTables() map[int]*Table {
 
 func (ts *Tables) ReleaseExpireTable(tid int) {
 	ts.mu.Lock()
-	defer ts.mu.Unlock()
+	t := ts.Tables[tid]
 	delete(ts.Tables, tid)
 	delete(ts.expires, tid)
-	ts.sortedTableId.Delete(tid)
+	ts.index.remove(tid)
+	ts.mu.Unlock()
+	if t != nil {
+		ts.emit(TableRemoved, tid, nil)
+	}
 }
 
 const defaultTableInPage = 9
 
 // for hprose
+// the page after the last one is the last page
 func (ts *Tables) Wrap(numOfTableInPage, pageNum int, filterWait bool) []map[string]interface{} {
-	ts.mu.RLock()
-	defer ts.mu.RUnlock()
-	tableIds := ts.sortedTableId.GetAll()
-	if filterWait {
-		tmpTableIds := make([]int, 0)
-		for _, tid := range tableIds {
-			if !ts.Tables[tid].IsStart() {
-				tmpTableIds = append(tmpTableIds, tid)
-			}
-		}
-		tableIds = tmpTableIds
-	}
-	l := len(tableIds)
-	if l == 0 {
-		return nil
-	}
 	if numOfTableInPage <= 0 {
 		numOfTableInPage = defaultTableInPage
 	}
 	if pageNum <= 0 {
 		pageNum = 1
 	}
-	res := make([]map[string]interface{}, 0)
-	start, end := (pageNum-1)*numOfTableInPage, pageNum*numOfTableInPage-1
-	switch l = l - 1; {
-	case l <= start:
-		end = l
-		start = 0
-		for start < end {
-			start += numOfTableInPage
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	q := TableQuery{WaitingOnly: filterWait}
+	start := (pageNum - 1) * numOfTableInPage
+	tableIds := make([]int, 0)
+	ts.index.scan(SortId, false, nil, func(key indexKey) bool {
+		if t := ts.Tables[key.tid]; t != nil && q.match(t) {
+			tableIds = append(tableIds, key.tid)
 		}
-		start -= numOfTableInPage
-	case l <= end:
-		end = l
+		return len(tableIds) < start+numOfTableInPage
+	})
+	l := len(tableIds)
+	if l == 0 {
+		return nil
 	}
-	if start < 0 {
-		start = 0
+	if start >= l {
+		start = (l - 1) / numOfTableInPage * numOfTableInPage
 	}
-	for _, index := range tableIds[start : end+1] {
-		res = append(res, ts.Tables[index].WrapTable())
+	res := make([]map[string]interface{}, 0)
+	for _, tid := range tableIds[start:] {
+		res = append(res, ts.Tables[tid].WrapTable())
 	}
 	return res
 }
@@ -243,20 +142,40 @@ func (ts *Tables) GetTableById(id int) *Table {
 // delete the Table
 func (ts *Tables) DelTable(id int) {
 	ts.mu.Lock()
-	defer ts.mu.Unlock()
+	t := ts.Tables[id]
 	delete(ts.Tables, id)
-	ts.sortedTableId.Delete(id)
+	ts.index.remove(id)
+	ts.mu.Unlock()
+	if t != nil {
+		ts.emit(TableRemoved, id, nil)
+	}
 }
 
 // create a new Table
 func (ts *Tables) NewTable(id int, title, host string, bet int) error {
-	ts.mu.Lock()
-	defer ts.mu.Unlock()
-	if _, ok := ts.Tables[id]; ok {
-		return ErrExisted
+	return ts.NewTableWithSettings(id, title, host, bet, DefaultTableSettings())
+}
+
+// create a new Table with the settings, the settings should be validated
+func (ts *Tables) NewTableWithSettings(id int, title, host string, bet int, settings TableSettings) error {
+	return ts.addTable(newTable(id, title, host, bet, settings))
+}
+
+func (ts *Tables) addTable(t *Table) error {
+	if err := func() error {
+		ts.mu.Lock()
+		defer ts.mu.Unlock()
+		if _, ok := ts.Tables[t.tId]; ok {
+			return ErrExisted
+		}
+		t.onChange = ts.tableChanged
+		ts.Tables[t.tId] = t
+		ts.index.add(t)
+		return nil
+	}(); err != nil {
+		return err
 	}
-	ts.Tables[id] = newTable(id, title, host, bet)
-	ts.sortedTableId.Add(id)
+	ts.emit(TableCreated, t.tId, t)
 	return nil
 }
 
@@ -275,6 +194,17 @@ func (ts *Tables) JoinTable(id int, u *User, isOb bool) error {
 	return t.Join(u)
 }
 
+// all the tables
+func (ts *Tables) GetAll() []*Table {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	res := make([]*Table, 0, len(ts.Tables))
+	for _, t := range ts.Tables {
+		res = append(res, t)
+	}
+	return res
+}
+
 // number of tables
 func (ts *Tables) Length() int {
 	ts.mu.RLock()
@@ -292,6 +222,8 @@ const (
 	zoneWidth             = 10
 	defaultNumOfNextPiece = 5
 	defaultInterval       = 1000
+	defaultDuration       = 120
+	defaultKOLimit        = 5
 )
 
 type gameOverStatus int
@@ -312,6 +244,16 @@ type Table struct {
 	tStat  string
 	tBet   int
 	tHost  string
+	// settings chosen by the creator
+	settings TableSettings
+	// private table
+	privacy privacy
+	// owner, bans, seat lock and observer mute
+	moderation moderation
+	// score of the series
+	series series
+	// keep the index of the hall updated, see Tables.addTable
+	onChange func(*Table)
 	// observers
 	obs *obs
 	// player 1p, 2p
@@ -322,35 +264,60 @@ type Table struct {
 	ready1p, ready2p bool
 	startTime        int64
 	// timer
-	timer               *timer.Timer
+	clock timer.Clock
+	timer *timer.Timer
+	// cancelled when the game stops, so that UpdateTimer quits
+	timerCtx            context.Context
+	cancelTimer         context.CancelFunc
 	remainedSeconds     int
 	RemainedSecondsChan chan int
 	// game over
 	GameoverChan chan gameOverStatus
 }
 
-func newTable(id int, title, host string, bet int) *Table {
+func newTable(id int, title, host string, bet int, settings TableSettings) *Table {
 	return &Table{
 		tId:                 id,
 		tTitle:              title,
 		tStat:               statWaiting,
 		tBet:                bet,
 		tHost:               host,
+		settings:            settings,
+		moderation:          newModeration(-1),
 		obs:                 NewObs(),
 		startTime:           time.Now().Unix(),
-		remainedSeconds:     120,
+		remainedSeconds:     settings.Duration,
+		clock:               timer.RealClock,
 		timer:               timer.NewTimer(1000),
 		RemainedSecondsChan: make(chan int, 1<<3),
 		GameoverChan:        make(chan gameOverStatus, 1<<3),
 	}
 }
 
+// set the clock of the table timer and the games, should be called before the game starts
+func (t *Table) SetClock(clock timer.Clock) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if clock == t.clock {
+		return
+	}
+	t.timer.Stop()
+	t.clock = clock
+	t.timer = timer.NewTimerWithClock(clock, 1000)
+	t.startTime = clock.Now().Unix()
+}
+
 func (t *Table) UpdateTimer() {
+	t.mu.Lock()
+	ctx, tm := t.timerCtx, t.timer
+	t.mu.Unlock()
+	if ctx == nil {
+		return
+	}
 	for {
-		if t.timer.IsPaused() {
+		if tm.WaitContext(ctx) != nil {
 			return
 		}
-		t.timer.Wait()
 		if b := func() bool {
 			t.mu.Lock()
 			defer t.mu.Unlock()
@@ -371,16 +338,22 @@ func (t *Table) WrapTable() map[string]interface{} {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	return map[string]interface{}{
-		"table_bet":      t.tBet,
-		"table_id":       t.tId,
-		"table_host":     t.tHost,
-		"table_status":   t.tStat,
-		"table_title":    t.tTitle,
-		"table_1p":       t._1p,
-		"table_2p":       t._2p,
-		"table_1p_ready": t.ready1p,
-		"table_2p_ready": t.ready2p,
-		"table_obs":      t.obs.Wrap(),
+		"table_bet":         t.tBet,
+		"table_id":          t.tId,
+		"table_host":        t.tHost,
+		"table_status":      t.tStat,
+		"table_title":       t.tTitle,
+		"table_1p":          t._1p,
+		"table_2p":          t._2p,
+		"table_1p_ready":    t.ready1p,
+		"table_2p_ready":    t.ready2p,
+		"table_obs":         t.obs.Wrap(),
+		"table_settings":    t.settings.Wrap(),
+		"table_private":     t.privacy.private,
+		"table_owner":       t.moderation.owner,
+		"table_seat_locked": t.moderation.seatLocked,
+		"table_obs_muted":   t.moderation.obsMuted,
+		"table_series":      t.wrapSeries(),
 	}
 }
 
@@ -402,12 +375,14 @@ func (t *Table) WrapTable() map[string]interface{} {
 func (t *Table) StartGame() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.g1p, _ = tetris.NewGame(zoneHeight, zoneWidth, defaultNumOfNextPiece, defaultInterval)
-	t.g2p, _ = tetris.NewGame(zoneHeight, zoneWidth, defaultNumOfNextPiece, defaultInterval)
+	s := t.settings
+	t.g1p, _ = tetris.NewGameWithClock(t.clock, s.Height, s.Width, s.NextCount, s.Interval)
+	t.g2p, _ = tetris.NewGameWithClock(t.clock, s.Height, s.Width, s.NextCount, s.Interval)
+	t.timerCtx, t.cancelTimer = context.WithCancel(context.Background())
 	t.timer.Start()
 	t.g1p.Start()
 	t.g2p.Start()
-	t.startTime = time.Now().Unix()
+	t.startTime = t.clock.Now().Unix()
 	t.tStat = statInGame
 }
 
@@ -415,12 +390,31 @@ func (t *Table) StartGame() {
 func (t *Table) StopGame() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	t.stopGame()
+}
+
+// stop the game if it is started, return false if it is already stopped
+// the game over and the forfeit may race, only the one stopping the game settles it
+func (t *Table) StopGameIfStarted() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tStat != statInGame {
+		return false
+	}
+	t.stopGame()
+	return true
+}
+
+func (t *Table) stopGame() {
 	t.timer.Pause()
 	t.timer.Reset()
+	if t.cancelTimer != nil {
+		t.cancelTimer()
+	}
 	t.g1p.Stop()
 	t.g2p.Stop()
 	t.tStat = statWaiting
-	t.startTime = time.Now().Unix()
+	t.startTime = t.clock.Now().Unix()
 }
 
 // reset the table
@@ -431,12 +425,13 @@ func (t *Table) ResetTable() {
 	t.g2p = nil
 	t.ready1p = false
 	t.ready2p = false
-	t.remainedSeconds = 120
+	t.remainedSeconds = t.settings.Duration
 	t.tStat = statWaiting
 }
 
 // set ready
 func (t *Table) SwitchReady(uid int) {
+	defer t.changed()
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	if uid < 0 {
@@ -465,6 +460,12 @@ const (
 
 var MaxDurationOfTable = maxGameDurationInSecs + maxIdleDurationInSecs
 
+func (t *Table) GetSettings() TableSettings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.settings
+}
+
 func (t *Table) GetHost() string {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -477,6 +478,13 @@ func (t *Table) GetIp() string {
 	return strings.Split(t.tHost, ":")[0]
 }
 
+// unix time when the game starts or stops
+func (t *Table) GetStartTime() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.startTime
+}
+
 func (t *Table) GetTid() int {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -487,7 +495,7 @@ func (t *Table) GetTid() int {
 func (t *Table) Expire() bool {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	tDur := time.Now().Unix() - t.startTime
+	tDur := t.clock.Now().Unix() - t.startTime
 	// if the game is start and have been played for longer than 300 seconds
 	// or if the game is not start for 3600 seconds -> 1 hour
 	// if the table has no players for 10 seconds, release it
@@ -497,7 +505,7 @@ func (t *Table) Expire() bool {
 		return tDur > maxNoPlayerDurationInSecs
 	}
 	if t.tStat == statInGame {
-		return tDur > maxGameDurationInSecs
+		return tDur > int64(maxGameDurationInSecs-defaultDuration+t.settings.Duration)
 	}
 	return tDur > maxIdleDurationInSecs
 }
@@ -511,24 +519,30 @@ func (t *Table) IsStart() bool {
 
 // start the game in the table
 func (t *Table) Start() {
+	defer t.changed()
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.startTime = time.Now().Unix()
+	if t.series.over {
+		t.resetSeries()
+	}
+	t.startTime = t.clock.Now().Unix()
 	t.tStat = statInGame
 }
 
 // stop the game
 func (t *Table) Stop() {
+	defer t.changed()
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.ready1p = false
 	t.ready2p = false
 	t.tStat = statWaiting
-	t.startTime = time.Now().Unix()
+	t.startTime = t.clock.Now().Unix()
 }
 
 // ob join the table
 func (t *Table) JoinOB(u *User) {
+	defer t.changed()
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.obs.Join(u)
@@ -538,14 +552,24 @@ func (t *Table) JoinOB(u *User) {
 func (t *Table) IsFull() bool {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	return t.isFull()
+}
+
+func (t *Table) isFull() bool {
+	if t.moderation.seatLocked {
+		return t._1p != nil || t._2p != nil
+	}
 	return t._1p != nil && t._2p != nil
 }
 
 // player join the Table
 func (t *Table) Join(u *User) (err error) {
+	defer t.changed()
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	switch {
+	case t.moderation.seatLocked && (t._1p != nil || t._2p != nil):
+		err = ErrRoomFull
 	case t._1p == nil:
 		t._1p = u
 	case t._2p == nil:
@@ -558,6 +582,7 @@ func (t *Table) Join(u *User) (err error) {
 
 // quit a user
 func (t *Table) Quit(uid int) {
+	defer t.changed()
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	if uid < 0 {
@@ -568,13 +593,16 @@ func (t *Table) Quit(uid int) {
 		// t._1p.Close()
 		t._1p = nil
 		t.ready1p = false
+		t.resetSeries()
 	case t._2p.GetUid():
 		// t._2p.Close()
 		t._2p = nil
 		t.ready2p = false
+		t.resetSeries()
 	default:
 		t.obs.Quit(uid)
 	}
+	t.handOverOnQuit(uid)
 }
 
 // check if the table does not have player
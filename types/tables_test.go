@@ -0,0 +1,76 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gogames/go_tetris/tetris"
+	"github.com/gogames/go_tetris/timer"
+)
+
+func drainGame(g *tetris.Game, done chan bool) {
+	for {
+		select {
+		case <-g.MsgChan:
+		case <-g.AttackChan:
+		case <-g.BeingKOChan:
+		case <-g.GameoverChan:
+		case <-done:
+			return
+		}
+	}
+}
+
+// the match lasts for 120 seconds on the fake clock
+func Test_TableTimer(t *testing.T) {
+	fc := timer.NewFakeClock(time.Unix(0, 0))
+	table := newTable(1, "", "", 0, DefaultTableSettings())
+	table.SetClock(fc)
+	table.StartGame()
+	defer table.StopGame()
+
+	done := make(chan bool)
+	defer close(done)
+	go drainGame(table.g1p, done)
+	go drainGame(table.g2p, done)
+	go table.UpdateTimer()
+
+	for want := 119; want > 0; want-- {
+		fc.Advance(time.Second)
+		if s := <-table.RemainedSecondsChan; s != want {
+			t.Fatalf("the remained seconds should be %d, got %d", want, s)
+		}
+	}
+	fc.Advance(time.Second)
+	select {
+	case stat := <-table.GameoverChan:
+		if stat != GameoverNormal {
+			t.Errorf("the game should be over normally, got %v", stat)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the game should be over in 120 seconds")
+	}
+	if !table.Expire() {
+		t.Error("the table has no players for more than 10 seconds, it should expire")
+	}
+}
+
+// the game over and the forfeit race, only one of them stops the game
+func Test_StopGameIfStarted(t *testing.T) {
+	table := newTable(1, "", "", 0, DefaultTableSettings())
+	table.SetClock(timer.NewFakeClock(time.Unix(0, 0)))
+	if table.StopGameIfStarted() {
+		t.Fatal("the game is not started")
+	}
+	table.StartGame()
+	stopped := make(chan bool)
+	for i := 0; i < 2; i++ {
+		go func() { stopped <- table.StopGameIfStarted() }()
+	}
+	if a, b := <-stopped, <-stopped; a == b {
+		t.Errorf("only one should stop the game, got %v and %v", a, b)
+	}
+	if table.IsStart() {
+		t.Error("the game should be stopped")
+	}
+}
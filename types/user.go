@@ -404,6 +404,13 @@ func (u *User) GetBalance() int {
 	return u.Balance
 }
 
+// get current level
+func (u *User) GetLevel() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.Level
+}
+
 // get current freezed
 func (u *User) GetFreezed() int {
 	u.mu.Lock()
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"bytes"
+
+	"github.com/gogames/go_tetris/errcode"
+	"github.com/hprose/hprose-go/hprose"
+)
+
+// set by the servers before invoking, the language of the session
+const langHeader = "X-Lang"
+
+// tags of the hprose error response: 'E' string 'z'
+const (
+	hproseTagError = 'E'
+	hproseTagEnd   = 'z'
+)
+
+// the language of the session, set in OnBeforeInvoke
+func SetRequestLanguage(ctx interface{}, lang string) {
+	if lang != "" {
+		getContext(ctx).Request.Header.Set(langHeader, lang)
+	}
+}
+
+// the language of the session, or of Accept-Language
+func RequestLanguage(ctx interface{}) string {
+	req := getContext(ctx).Request
+	if lang := req.Header.Get(langHeader); lang != "" {
+		return lang
+	}
+	return errcode.AcceptLanguage(req.Header.Get("Accept-Language"))
+}
+
+// localise the errors to the client, the code and the message in the language of the request
+type ErrorFilter struct{}
+
+func (ErrorFilter) InputFilter(data []byte, ctx interface{}) []byte { return data }
+
+func (ErrorFilter) OutputFilter(data []byte, ctx interface{}) []byte {
+	if len(data) < 2 || data[0] != hproseTagError || data[len(data)-1] != hproseTagEnd {
+		return data
+	}
+	var text string
+	if err := hprose.Unmarshal(data[1:len(data)-1], &text); err != nil {
+		return data
+	}
+	b, err := hprose.Marshal(errcode.Localize(text, RequestLanguage(ctx)).String())
+	if err != nil {
+		return data
+	}
+	return bytes.Join([][]byte{{hproseTagError}, b, {hproseTagEnd}}, nil)
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/gogames/go_tetris/errcode"
+	"github.com/hprose/hprose-go/hprose"
+)
+
+// the error response as the hprose service writes it, 'E' string 'z', the length is in utf-16
+func hproseErrorResponse(text string) []byte {
+	n := len(utf16.Encode([]rune(text)))
+	return []byte("Es" + strconv.Itoa(n) + `"` + text + `"z`)
+}
+
+// the error the hprose client gets from the response
+func decodeErrorResponse(t *testing.T, data []byte) errcode.Response {
+	if data[0] != hproseTagError || data[len(data)-1] != hproseTagEnd {
+		t.Fatalf("not an error response %q", data)
+	}
+	var text string
+	if err := hprose.Unmarshal(data[1:len(data)-1], &text); err != nil {
+		t.Fatalf("can not decode the error response %q: %v", data, err)
+	}
+	var r errcode.Response
+	if err := json.Unmarshal([]byte(text), &r); err != nil {
+		t.Fatalf("the error should be the json of the code and the message, got %q", text)
+	}
+	return r
+}
+
+func Test_ErrorFilter(t *testing.T) {
+	var filter ErrorFilter
+	e := errcode.New(errcode.TableNotExist, 12)
+	ctx := newPrivCtx()
+	ctx.Request.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	// the message in chinese by default, and in the language of the request to the client
+	out := filter.OutputFilter(hproseErrorResponse(e.Error()), ctx)
+	if string(out) != string(hproseErrorResponse(e.In("en").String())) {
+		t.Errorf("unexpected response %q", out)
+	}
+	if r := decodeErrorResponse(t, out); r.Code != errcode.TableNotExist || r.Message != "the table 12 does not exist, please join another one" {
+		t.Errorf("the error should be in en, got %+v", r)
+	}
+
+	// the language of the session goes first
+	SetRequestLanguage(ctx, errcode.Default)
+	if r := decodeErrorResponse(t, filter.OutputFilter(hproseErrorResponse(e.Error()), ctx)); r.Message != e.Message(errcode.Default) {
+		t.Errorf("the error should be in the language of the session, got %+v", r)
+	}
+
+	// a panic without a code is Unknown, the text is kept
+	if r := decodeErrorResponse(t, filter.OutputFilter(hproseErrorResponse("错误"), ctx)); r.Code != errcode.Unknown || len(r.Args) != 1 || r.Args[0] != "错误" {
+		t.Errorf("unexpected unknown error %+v", r)
+	}
+
+	// the other responses are not changed
+	others := []string{`Rs2"ok"z`, "Rz", "E", `Es9"not hprosez`}
+	for _, data := range others {
+		if out := filter.OutputFilter([]byte(data), ctx); string(out) != data {
+			t.Errorf("the response %q should not be changed, got %q", data, out)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"sync"
+
+	"github.com/gogames/go_tetris/timer"
+)
+
+// the nonces used before they expire, to reject the replays
+type NonceStore struct {
+	used   map[string]int64 // nonce -> unix time of expiry
+	lastGc int64
+	clock  timer.Clock
+	mu     sync.Mutex
+}
+
+func NewNonceStore() *NonceStore { return NewNonceStoreWithClock(timer.RealClock) }
+
+func NewNonceStoreWithClock(clock timer.Clock) *NonceStore {
+	return &NonceStore{used: make(map[string]int64), clock: clock}
+}
+
+// use the nonce until exp, return false if it is already used
+func (ns *NonceStore) Use(nonce string, exp int64) bool {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	// delete the expired nonces at most once a second
+	if now := ns.clock.Now().Unix(); now > ns.lastGc {
+		ns.lastGc = now
+		for n, e := range ns.used {
+			if e < now {
+				delete(ns.used, n)
+			}
+		}
+	}
+	if _, ok := ns.used[nonce]; ok {
+		return false
+	}
+	ns.used[nonce] = exp
+	return true
+}
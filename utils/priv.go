@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gogames/go_tetris/timer"
+	"github.com/hprose/hprose-go/hprose"
+	"github.com/xxtea/xxtea-go/xxtea"
+)
+
+// the private rpc between the auth server and the game servers
+// every game server has its own key, the requests and the responses of both directions are signed by it
+//
+//	frame = id.timestamp.nonce.signature "\n" body
+//
+// the body is encrypted by xxtea with the key, the signature is the hmac-sha256 of id.timestamp.nonce and the body
+// a frame is rejected if the signature is wrong, out of the time window, or the nonce is used
+const privSkew = 30 // seconds
+
+// set by the filter of a server, the id of the verified game server
+const privIdHeader = "X-Priv-Id"
+
+var (
+	errPrivFrame     = fmt.Errorf("the private rpc frame is malformed")
+	errPrivSignature = fmt.Errorf("the private rpc frame is not signed by a known key")
+	errPrivExpired   = fmt.Errorf("the private rpc frame is out of the time window")
+	errPrivReplay    = fmt.Errorf("the private rpc frame is replayed")
+)
+
+type PrivFilter struct {
+	id     string // the client signs by the key of id, the server signs the response by the key of the request
+	keys   map[string][]byte
+	nonces *NonceStore
+	clock  timer.Clock
+	Reject func(err error) // called with the reason of a rejected frame, optional
+}
+
+// the filter of a client, the game server to the auth server or the auth server to a game server
+func NewPrivClientFilter(id string, key []byte) *PrivFilter {
+	return newPrivFilter(id, map[string][]byte{id: key}, timer.RealClock)
+}
+
+// the filter of a server, accept the frames signed by any of the keys
+func NewPrivServerFilter(keys map[string][]byte) *PrivFilter {
+	return newPrivFilter("", keys, timer.RealClock)
+}
+
+func newPrivFilter(id string, keys map[string][]byte, clock timer.Clock) *PrivFilter {
+	return &PrivFilter{id: id, keys: keys, nonces: NewNonceStoreWithClock(clock), clock: clock}
+}
+
+// parse the keys of the game servers from the configuration, "id:key,id:key"
+func ParsePrivKeys(conf string) (map[string][]byte, error) {
+	keys, err := parseKeys(conf, "private key")
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		m[k.id] = k.key
+	}
+	return m, nil
+}
+
+// the id of the game server verified by the filter of the server, empty if not verified
+func PrivIdOf(ctx interface{}) string {
+	return getContext(ctx).Request.Header.Get(privIdHeader)
+}
+
+func (f *PrivFilter) InputFilter(data []byte, ctx interface{}) []byte {
+	isServer := f.id == ""
+	if isServer {
+		// do not trust the header from the client
+		getContext(ctx).Request.Header.Del(privIdHeader)
+	}
+	id, body, err := f.open(data)
+	if err != nil {
+		if f.Reject != nil {
+			f.Reject(err)
+		}
+		return nil
+	}
+	if isServer {
+		getContext(ctx).Request.Header.Set(privIdHeader, id)
+	}
+	return body
+}
+
+func (f *PrivFilter) OutputFilter(data []byte, ctx interface{}) []byte {
+	id := f.id
+	if id == "" {
+		id = PrivIdOf(ctx)
+	}
+	key, ok := f.keys[id]
+	if !ok {
+		// the request is rejected, the client rejects the unsigned response as well
+		return data
+	}
+	return f.seal(id, key, data)
+}
+
+func (f *PrivFilter) seal(id string, key, data []byte) []byte {
+	body := xxtea.Encrypt(data, key)
+	head := fmt.Sprintf("%s.%d.%s", id, f.clock.Now().Unix(), RandString(16))
+	return append([]byte(head+"."+privMac(key, head, body)+"\n"), body...)
+}
+
+func (f *PrivFilter) open(data []byte) (id string, body []byte, err error) {
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return "", nil, errPrivFrame
+	}
+	vals := strings.Split(string(data[:i]), ".")
+	if len(vals) != 4 {
+		return "", nil, errPrivFrame
+	}
+	id, body = vals[0], data[i+1:]
+	key, ok := f.keys[id]
+	if !ok || !hmac.Equal([]byte(vals[3]), []byte(privMac(key, strings.Join(vals[:3], "."), body))) {
+		return "", nil, errPrivSignature
+	}
+	ts, err := strconv.ParseInt(vals[1], 10, 64)
+	if err != nil {
+		return "", nil, errPrivFrame
+	}
+	if now := f.clock.Now().Unix(); ts < now-privSkew || ts > now+privSkew {
+		return "", nil, errPrivExpired
+	}
+	if !f.nonces.Use(id+"."+vals[2], ts+privSkew) {
+		return "", nil, errPrivReplay
+	}
+	return id, xxtea.Decrypt(body, key), nil
+}
+
+func privMac(key []byte, head string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(head))
+	mac.Write(body)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// the clients of the private rpc trust only the ca of the locally generated certificates, see cmd/privcert
+func PrivTlsConfig(caFile string) (*tls.Config, error) {
+	b, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificate in %s", caFile)
+	}
+	return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
+}
+
+// the http client of hprose
+type tlsClient interface {
+	SetTLSClientConfig(config *tls.Config)
+}
+
+// a client of the private rpc at host, over tls if conf is not nil
+func NewPrivClient(host string, conf *tls.Config, filter *PrivFilter) hprose.Client {
+	scheme := "http"
+	if conf != nil {
+		scheme = "https"
+	}
+	c := hprose.NewHttpClient(scheme + "://" + host + "/")
+	if conf != nil {
+		tc, ok := c.(tlsClient)
+		if !ok {
+			panic("the rpc client does not support tls")
+		}
+		tc.SetTLSClientConfig(conf)
+	}
+	c.SetKeepAlive(true)
+	c.SetFilter(filter)
+	return c
+}
+
+// serve the private rpc, over tls if the certificate is set
+func ServePriv(addr, certFile, keyFile string, h http.Handler) error {
+	if certFile == "" {
+		return http.ListenAndServe(addr, h)
+	}
+	return http.ListenAndServeTLS(addr, certFile, keyFile, h)
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gogames/go_tetris/timer"
+	"github.com/hprose/hprose-go/hprose"
+)
+
+func newPrivCtx() *hprose.HttpContext {
+	return &hprose.HttpContext{Request: httptest.NewRequest("POST", "/", nil), Response: httptest.NewRecorder()}
+}
+
+func Test_PrivFilter(t *testing.T) {
+	fc := timer.NewFakeClock(time.Unix(1000, 0))
+	keys := map[string][]byte{"game1": []byte("key of game1"), "game2": []byte("key of game2")}
+	client := newPrivFilter("game1", map[string][]byte{"game1": keys["game1"]}, fc)
+	server := newPrivFilter("", keys, fc)
+
+	// the request and the response
+	req := client.OutputFilter([]byte("request"), nil)
+	ctx := newPrivCtx()
+	if b := server.InputFilter(req, ctx); string(b) != "request" {
+		t.Fatalf("the request should be verified, got %q", b)
+	}
+	if id := PrivIdOf(ctx); id != "game1" {
+		t.Errorf("the id should be game1, got %q", id)
+	}
+	if b := client.InputFilter(server.OutputFilter([]byte("response"), ctx), nil); string(b) != "response" {
+		t.Errorf("the response should be verified, got %q", b)
+	}
+
+	// replayed
+	ctx = newPrivCtx()
+	if b := server.InputFilter(req, ctx); b != nil || PrivIdOf(ctx) != "" {
+		t.Error("the replayed request should be rejected")
+	}
+
+	// changed body
+	req = client.OutputFilter([]byte("request"), nil)
+	req[len(req)-1] ^= 1
+	if b := server.InputFilter(req, newPrivCtx()); b != nil {
+		t.Error("the changed request should be rejected")
+	}
+
+	// another key with the id of game1
+	rogue := newPrivFilter("game1", map[string][]byte{"game1": []byte("guessed")}, fc)
+	if b := server.InputFilter(rogue.OutputFilter([]byte("request"), nil), newPrivCtx()); b != nil {
+		t.Error("the request signed by another key should be rejected")
+	}
+
+	// out of the time window
+	req = client.OutputFilter([]byte("request"), nil)
+	fc.Advance((privSkew + 1) * time.Second)
+	if b := server.InputFilter(req, newPrivCtx()); b != nil {
+		t.Error("the expired request should be rejected")
+	}
+
+	// the header from the client is not trusted, nor signed
+	ctx = newPrivCtx()
+	ctx.Request.Header.Set(privIdHeader, "game2")
+	if b := server.InputFilter([]byte("request"), ctx); b != nil || PrivIdOf(ctx) != "" {
+		t.Error("the unsigned request should be rejected")
+	}
+	if b := server.OutputFilter([]byte("error"), ctx); !bytes.Equal(b, []byte("error")) {
+		t.Errorf("the response of a rejected request should not be signed, got %q", b)
+	}
+}
+
+func Test_ParsePrivKeys(t *testing.T) {
+	keys, err := ParsePrivKeys("game1:key1, game2:key2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || string(keys["game1"]) != "key1" || string(keys["game2"]) != "key2" {
+		t.Errorf("the keys should be of game1 and game2, got %v", keys)
+	}
+	if _, err := ParsePrivKeys("game1:key1,game1:key2"); err == nil {
+		t.Error("the duplicated id should be rejected")
+	}
+	if _, err := PrivTlsConfig("not exist"); err == nil {
+		t.Error("the missing ca should be an error")
+	}
+}
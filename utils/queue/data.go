@@ -3,6 +3,7 @@ package queue
 import (
 	"fmt"
 	"sync"
+	"time"
 )
 
 // data belong to whom
@@ -17,52 +18,123 @@ const (
 )
 
 // data
+// the data of a stream, e.g. the zone frames of 1p, is superseded by the next keyframe of the stream
 type data struct {
-	belong DataBelong
-	data   interface{}
+	belong   DataBelong
+	data     interface{}
+	stream   string
+	keyframe bool
+	dropped  bool // superseded by a keyframe, the readers skip it
 }
 
-func newData(d interface{}, belong DataBelong) data {
-	return data{belong: belong, data: d}
+func (d data) isBelongTo(belong DataBelong) bool { return d.belong == BelongToAll || d.belong == belong }
+
+// the kinds of the readers of the data
+var belongs = []DataBelong{BelongTo1p, BelongTo2p, BelongToObs}
+
+// a reader, e.g. a session, and the data it has got
+type reader struct {
+	belong DataBelong
+	acked  int // the reader has got the data before it
 }
 
-func (d data) isBelongTo(belong DataBelong) bool { return d.belong == BelongToAll || d.belong == belong }
+// at most maxDatas data are kept for a table, the readers behind them should resync
+const maxDatas = 1 << 12
 
-// datas
+// datas is a ring buffer of data, indexed by sequence
+// the data got by all the registered readers of it and the dropped data are trimmed
 type datas struct {
-	d  []data
-	mu sync.RWMutex
+	d         []data
+	head      int                // sequence of the oldest data
+	next      int                // sequence of the next data
+	readers   map[string]*reader // the registered readers by id
+	floor     map[DataBelong]int // the readers from an index before it missed some data
+	keyframes map[string]int     // sequence of the last keyframe of the streams
+	changed   chan struct{}      // closed when a data is set
+	mu        sync.RWMutex
 }
 
 func newDatas() *datas {
 	return &datas{
-		d: make([]data, 0),
+		d:         make([]data, maxDatas),
+		readers:   make(map[string]*reader),
+		floor:     make(map[DataBelong]int),
+		keyframes: make(map[string]int),
+		changed:   make(chan struct{}),
 	}
 }
 
-// length of data
+func (d *datas) at(seq int) *data { return &d.d[seq%len(d.d)] }
+
+// length of data, the sequence of the next data
 func (d *datas) length() int {
 	if d == nil {
 		return -1
 	}
 	d.mu.RLock()
 	defer d.mu.RUnlock()
-	return len(d.d)
+	return d.next
+}
+
+// number of data kept
+func (d *datas) size() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.next - d.head
 }
 
-// get data from index
-func (d *datas) getDataFromIndex(index int, belong DataBelong) (res []interface{}, l int) {
+// register the reader, the data from the returned index are kept until it gets them
+func (d *datas) addReader(id string, belong DataBelong) int {
+	if d == nil {
+		return -1
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.readers[id] = &reader{belong: belong, acked: d.next}
+	return d.next
+}
+
+// the reader is gone, its data are no longer kept
+func (d *datas) removeReader(id string) {
 	if d == nil {
-		fmt.Printf("datas is nil, why get data for belong %v from index %d\n", belong, index)
 		return
 	}
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-	l = len(d.d)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.readers, id)
+	d.trim()
+}
+
+// get data from index, the reader acknowledges the data before index
+// an unknown reader is registered from index
+// resync if the reader misses some data, then it should read from l and ask for the keyframes
+func (d *datas) getDataFromIndex(index int, id string, belong DataBelong) (res []interface{}, l int, resync bool) {
+	if d == nil {
+		fmt.Printf("datas is nil, why get data for reader %s from index %d\n", id, index)
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	l = d.next
+	if index < d.floor[belong] || index > d.next {
+		return nil, l, true
+	}
+	r, ok := d.readers[id]
+	if !ok {
+		r = &reader{belong: belong, acked: index}
+		d.readers[id] = r
+	}
+	if index > r.acked {
+		r.acked = index
+		d.trim()
+	}
+	if index < d.head {
+		index = d.head
+	}
 	res = make([]interface{}, 0)
 	for i := index; i < l; i++ {
-		if d.d[i].isBelongTo(belong) {
-			res = append(res, d.d[i].data)
+		if dt := d.at(i); !dt.dropped && dt.isBelongTo(belong) {
+			res = append(res, dt.data)
 		}
 	}
 	if len(res) == 0 {
@@ -72,12 +144,85 @@ func (d *datas) getDataFromIndex(index int, belong DataBelong) (res []interface{
 }
 
 func (d *datas) setData(data interface{}, belong DataBelong) {
+	d.setFrame("", false, data, belong)
+}
+
+// set the data of a stream, a keyframe drops the former data of the stream
+func (d *datas) setFrame(stream string, keyframe bool, dt interface{}, belong DataBelong) {
 	if d == nil {
 		return
 	}
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	d.d = append(d.d, newData(data, belong))
+	if d.next-d.head == len(d.d) {
+		d.drop(d.head)
+		d.head++
+	}
+	if keyframe {
+		last, ok := d.keyframes[stream]
+		if !ok || last < d.head {
+			last = d.head
+		}
+		for i := last; i < d.next; i++ {
+			if p := d.at(i); p.stream == stream {
+				p.dropped, p.data = true, nil
+			}
+		}
+		d.keyframes[stream] = d.next
+	}
+	*d.at(d.next) = data{belong: belong, data: dt, stream: stream, keyframe: keyframe}
+	d.next++
+	d.trim()
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+// wait for the data from index, at most timeout
+// it may return nil before timeout if the new data is not for the reader
+func (d *datas) wait(index int, id string, belong DataBelong, timeout time.Duration) ([]interface{}, int, bool) {
+	if d == nil {
+		time.Sleep(timeout)
+		return nil, index, false
+	}
+	d.mu.RLock()
+	changed := d.changed
+	d.mu.RUnlock()
+	if res, l, resync := d.getDataFromIndex(index, id, belong); res != nil || resync {
+		return res, l, resync
+	}
+	select {
+	case <-changed:
+	case <-time.After(timeout):
+	}
+	return d.getDataFromIndex(index, id, belong)
+}
+
+// trim the data from the head, if dropped or got by all the registered readers of it
+// one slow reader keeps the data of the others, it is bounded by maxDatas
+func (d *datas) trim() {
+	for ; d.head < d.next; d.head++ {
+		if dt := d.at(d.head); !dt.dropped {
+			for _, r := range d.readers {
+				if dt.isBelongTo(r.belong) && r.acked <= d.head {
+					return
+				}
+			}
+		}
+		d.drop(d.head)
+	}
+}
+
+// the data is removed from the buffer, the readers before it miss the data
+func (d *datas) drop(seq int) {
+	dt := d.at(seq)
+	if !dt.dropped {
+		for _, r := range belongs {
+			if dt.isBelongTo(r) && d.floor[r] <= seq {
+				d.floor[r] = seq + 1
+			}
+		}
+	}
+	*dt = data{}
 }
 
 // table datas
@@ -89,30 +234,30 @@ type tableDatas struct {
 func NewTableDatas() *tableDatas { return &tableDatas{datas: make(map[int]*datas)} }
 
 // debug
-func (tds tableDatas) PrintForDebug() string {
+func (tds *tableDatas) PrintForDebug() string {
 	tds.mu.RLock()
 	defer tds.mu.RUnlock()
 	str := "Table datas now contains the following information:\n"
 	for tid, tinfo := range tds.datas {
-		str += fmt.Sprintf("	tableId: %d -> length of data is %d\n", tid, tinfo.length())
+		str += fmt.Sprintf("	tableId: %d -> length of data is %d, %d data kept\n", tid, tinfo.length(), tinfo.size())
 	}
 	return str
 }
 
 // get table data
-func (tds tableDatas) getTableData(tableId int) *datas {
+func (tds *tableDatas) getTableData(tableId int) *datas {
 	tds.mu.RLock()
 	defer tds.mu.RUnlock()
 	return tds.datas[tableId]
 }
 
 // next index
-func (tds tableDatas) Index(tableId int) int {
+func (tds *tableDatas) Index(tableId int) int {
 	return tds.getTableData(tableId).length()
 }
 
 // is table data exist
-func (tds tableDatas) IsTableExist(tableId int) bool { return tds.getTableData(tableId) != nil }
+func (tds *tableDatas) IsTableExist(tableId int) bool { return tds.getTableData(tableId) != nil }
 
 // new table data
 func (tds *tableDatas) NewTableData(tableId int) error {
@@ -132,12 +277,34 @@ func (tds *tableDatas) DeleteTable(tableId int) {
 	delete(tds.datas, tableId)
 }
 
-// Get data from index for belong
-func (tds tableDatas) GetData(tableId, index int, belong DataBelong) ([]interface{}, int) {
-	return tds.getTableData(tableId).getDataFromIndex(index, belong)
+// register the reader of the table, e.g. a session, return the index it should read from
+// the data are kept until all the registered readers of them get them
+func (tds *tableDatas) AddReader(tableId int, reader string, belong DataBelong) int {
+	return tds.getTableData(tableId).addReader(reader, belong)
+}
+
+// the reader quits the table
+func (tds *tableDatas) RemoveReader(tableId int, reader string) {
+	tds.getTableData(tableId).removeReader(reader)
+}
+
+// Get data from index for the reader of belong
+// resync if the reader is too far behind, it should read from the returned index and ask for the keyframes
+func (tds *tableDatas) GetData(tableId, index int, reader string, belong DataBelong) ([]interface{}, int, bool) {
+	return tds.getTableData(tableId).getDataFromIndex(index, reader, belong)
+}
+
+// wait for the data from index for the reader, instead of polling GetData
+func (tds *tableDatas) Wait(tableId, index int, reader string, belong DataBelong, timeout time.Duration) ([]interface{}, int, bool) {
+	return tds.getTableData(tableId).wait(index, reader, belong, timeout)
 }
 
 // set data
 func (tds *tableDatas) SetData(tableId int, data interface{}, belong DataBelong) {
 	tds.getTableData(tableId).setData(data, belong)
 }
+
+// set the data of a stream, the keyframe supersedes the former data of the stream
+func (tds *tableDatas) SetFrame(tableId int, stream string, keyframe bool, data interface{}, belong DataBelong) {
+	tds.getTableData(tableId).setFrame(stream, keyframe, data, belong)
+}
@@ -0,0 +1,145 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_DatasTrim(t *testing.T) {
+	d := newDatas()
+	for i := 0; i < 3; i++ {
+		d.setData(i, BelongToAll)
+	}
+	// no reader yet, nothing blocks the trimming
+	if d.size() != 0 || d.length() != 3 {
+		t.Fatalf("should keep no data, got %d of %d", d.size(), d.length())
+	}
+
+	// the readers block the trimming of their data
+	d.getDataFromIndex(3, "p1", BelongTo1p)
+	d.getDataFromIndex(3, "p2", BelongTo2p)
+	d.setData("all", BelongToAll)
+	d.setData("1p", BelongTo1p)
+	res, l, resync := d.getDataFromIndex(3, "p2", BelongTo2p)
+	if resync || len(res) != 1 || res[0] != "all" || l != 5 {
+		t.Fatalf("2p should get the data for all, got %v, %d, %v", res, l, resync)
+	}
+	d.getDataFromIndex(5, "p2", BelongTo2p)
+	if d.size() != 2 {
+		t.Errorf("1p has not got its data, got %d data kept", d.size())
+	}
+	d.getDataFromIndex(5, "p1", BelongTo1p)
+	if d.size() != 0 {
+		t.Errorf("all the data are acknowledged, got %d data kept", d.size())
+	}
+
+	// the observer joins late
+	if _, _, resync := d.getDataFromIndex(3, "ob", BelongToObs); !resync {
+		t.Error("the observer missed the data for all, should resync")
+	}
+	if _, _, resync := d.getDataFromIndex(6, "ob", BelongToObs); !resync {
+		t.Error("the index after the next data is incorrect, should resync")
+	}
+	if _, _, resync := d.getDataFromIndex(5, "ob", BelongToObs); resync {
+		t.Error("the observer should read from the next data")
+	}
+}
+
+// the data set after the reader is registered are kept until it reads them
+func Test_DatasSetBeforeRead(t *testing.T) {
+	d := newDatas()
+	d.setData("before", BelongToAll)
+	index := d.addReader("p1", BelongTo1p)
+	d.setData("refresh", BelongToAll)
+	d.setFrame("1p", true, "k1", BelongToAll)
+	if d.size() != 2 {
+		t.Fatalf("the data of the registered reader should be kept, got %d", d.size())
+	}
+	res, l, resync := d.getDataFromIndex(index, "p1", BelongTo1p)
+	if resync || len(res) != 2 || res[0] != "refresh" || res[1] != "k1" || l != 3 {
+		t.Fatalf("the first read should get the data set after the registration, got %v, %d, %v", res, l, resync)
+	}
+	d.getDataFromIndex(l, "p1", BelongTo1p)
+	if d.size() != 0 {
+		t.Errorf("the data are got, got %d data kept", d.size())
+	}
+}
+
+// a fast observer does not trim the data of a slow one
+func Test_DatasSlowReader(t *testing.T) {
+	d := newDatas()
+	fast, slow := d.addReader("fast", BelongToObs), d.addReader("slow", BelongToObs)
+	d.setData("a", BelongToObs)
+	d.setData("b", BelongToAll)
+	_, fast, _ = d.getDataFromIndex(fast, "fast", BelongToObs)
+	d.getDataFromIndex(fast, "fast", BelongToObs)
+	if d.size() != 2 {
+		t.Fatalf("the slow observer has not got the data, got %d data kept", d.size())
+	}
+	res, l, resync := d.getDataFromIndex(slow, "slow", BelongToObs)
+	if resync || len(res) != 2 || res[0] != "a" {
+		t.Fatalf("the slow observer should get the data without resync, got %v, %v", res, resync)
+	}
+	d.getDataFromIndex(l, "slow", BelongToObs)
+	if d.size() != 0 {
+		t.Errorf("all the observers got the data, got %d data kept", d.size())
+	}
+
+	// the removed reader does not keep the data
+	d.setData("c", BelongToObs)
+	d.getDataFromIndex(3, "fast", BelongToObs)
+	d.removeReader("slow")
+	if d.size() != 0 {
+		t.Errorf("the data of the removed reader should be trimmed, got %d data kept", d.size())
+	}
+}
+
+func Test_DatasKeyframe(t *testing.T) {
+	d := newDatas()
+	d.getDataFromIndex(0, "ob", BelongToObs)
+	d.setFrame("1p", true, "k1", BelongToAll)
+	d.setFrame("1p", false, "d1", BelongToAll)
+	d.setFrame("2p", true, "k2", BelongToAll)
+	d.setData("chat", BelongToAll)
+	d.setFrame("1p", true, "k3", BelongToAll)
+	res, _, resync := d.getDataFromIndex(0, "ob", BelongToObs)
+	if resync || len(res) != 3 || res[0] != "k2" || res[1] != "chat" || res[2] != "k3" {
+		t.Fatalf("the former frames of 1p should be dropped, got %v, %v", res, resync)
+	}
+}
+
+func Test_DatasOverflow(t *testing.T) {
+	d := newDatas()
+	d.getDataFromIndex(0, "p1", BelongTo1p)
+	for i := 0; i < maxDatas+10; i++ {
+		d.setData(i, BelongTo1p)
+	}
+	if d.size() != maxDatas {
+		t.Errorf("should keep %d data, got %d", maxDatas, d.size())
+	}
+	if _, l, resync := d.getDataFromIndex(0, "p1", BelongTo1p); !resync || l != maxDatas+10 {
+		t.Errorf("the reader is too far behind, got %d, %v", l, resync)
+	}
+	if res, _, resync := d.getDataFromIndex(10, "p1", BelongTo1p); resync || len(res) != maxDatas || res[0] != 10 {
+		t.Errorf("should get the data kept, got %d data, %v", len(res), resync)
+	}
+}
+
+func Test_DatasWait(t *testing.T) {
+	d := newDatas()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		d.setData("data", BelongToAll)
+	}()
+	start := time.Now()
+	res, l, _ := d.wait(0, "p1", BelongTo1p, time.Second)
+	if len(res) != 1 || l != 1 {
+		t.Fatalf("should get the data, got %v, %d", res, l)
+	}
+	if time.Since(start) >= time.Second {
+		t.Error("should not wait until timeout")
+	}
+	if res, _, _ := d.wait(1, "p1", BelongTo1p, 10*time.Millisecond); res != nil {
+		t.Errorf("should get nothing, got %v", res)
+	}
+}
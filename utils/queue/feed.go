@@ -0,0 +1,75 @@
+package queue
+
+import (
+	"sync"
+	"time"
+)
+
+// feed of events with sequence numbers, the readers get the events since their cursor
+// only the latest max events are kept, the readers behind them should resync
+type Feed struct {
+	events  []interface{}
+	base    int // sequence of events[0]
+	max     int
+	changed chan struct{} // closed when an event is appended
+	mu      sync.RWMutex
+}
+
+func NewFeed(max int) *Feed {
+	return &Feed{
+		events:  make([]interface{}, 0),
+		max:     max,
+		changed: make(chan struct{}),
+	}
+}
+
+// append an event, return its sequence
+func (f *Feed) Append(event interface{}) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, event)
+	if over := len(f.events) - f.max; over > 0 {
+		f.events = append(f.events[:0:0], f.events[over:]...)
+		f.base += over
+	}
+	close(f.changed)
+	f.changed = make(chan struct{})
+	return f.base + len(f.events) - 1
+}
+
+// the sequence of the next event
+func (f *Feed) Seq() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.base + len(f.events)
+}
+
+// the events since the cursor and the next cursor
+// resync if the cursor is too old or incorrect, then the reader should get all the data again
+func (f *Feed) Since(cursor int) (events []interface{}, next int, resync bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	next = f.base + len(f.events)
+	if cursor < f.base || cursor > next {
+		return nil, next, true
+	}
+	if cursor == next {
+		return nil, next, false
+	}
+	return append([]interface{}{}, f.events[cursor-f.base:]...), next, false
+}
+
+// wait for the events since the cursor, at most timeout
+func (f *Feed) Wait(cursor int, timeout time.Duration) (events []interface{}, next int, resync bool) {
+	f.mu.RLock()
+	changed := f.changed
+	f.mu.RUnlock()
+	if events, next, resync = f.Since(cursor); events != nil || resync {
+		return
+	}
+	select {
+	case <-changed:
+	case <-time.After(timeout):
+	}
+	return f.Since(cursor)
+}
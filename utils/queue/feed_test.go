@@ -0,0 +1,43 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Feed(t *testing.T) {
+	f := NewFeed(3)
+	if _, next, resync := f.Since(-1); !resync || next != 0 {
+		t.Fatalf("the first call should resync from 0, got %d, %v", next, resync)
+	}
+	for i := 0; i < 2; i++ {
+		f.Append(i)
+	}
+	events, next, resync := f.Since(0)
+	if resync || len(events) != 2 || next != 2 {
+		t.Fatalf("should get 2 events, got %v, %d, %v", events, next, resync)
+	}
+
+	// the old events are dropped
+	for i := 2; i < 5; i++ {
+		f.Append(i)
+	}
+	if _, next, resync := f.Since(1); !resync || next != 5 {
+		t.Errorf("the cursor 1 is too old, got %d, %v", next, resync)
+	}
+	if events, _, _ := f.Since(3); len(events) != 2 || events[0] != 3 {
+		t.Errorf("should get the events 3 and 4, got %v", events)
+	}
+
+	// long polling
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		f.Append(5)
+	}()
+	if events, next, _ := f.Wait(5, time.Second); len(events) != 1 || events[0] != 5 || next != 6 {
+		t.Errorf("should wait for the event 5, got %v, %d", events, next)
+	}
+	if events, _, _ := f.Wait(6, 10*time.Millisecond); events != nil {
+		t.Errorf("should get nothing after timeout, got %v", events)
+	}
+}
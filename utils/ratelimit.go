@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"math"
+	"time"
+
+	"github.com/gogames/go_tetris/errcode"
+	"github.com/gogames/go_tetris/utils/ratelimit"
+)
+
+// take a token of the rpc for the user, or the ip of the request if the user is empty
+// panic with the Retry-After header if limited
+func TakeToken(l *ratelimit.Limiter, method, user string, ctx interface{}) {
+	retryAfter, ok := l.Take(method, GetIp(ctx), user)
+	if ok {
+		return
+	}
+	err := ErrTooFrequent(retryAfter)
+	getContext(ctx).Response.Header().Set("Retry-After", err.Args[0])
+	panic(err)
+}
+
+// the error of a limited call, with the seconds to retry after
+func ErrTooFrequent(retryAfter time.Duration) *errcode.Error {
+	return errcode.New(errcode.TooFrequent, int(math.Ceil(retryAfter.Seconds())))
+}
@@ -0,0 +1,206 @@
+/*
+token bucket rate limiter of the public rpc, a bucket for each method and each user
+a bucket holds Burst tokens at most and refills Rate tokens a minute, a call takes a token
+a user is the uid if logged in, or the ip
+the policy of a user overrides the one of the method, e.g. "uid:1:Login" or "uid:1:*"
+*/
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogames/go_tetris/timer"
+)
+
+// the policy of the methods without their own
+const Default = "*"
+
+type Policy struct {
+	Rate  float64 // tokens a minute
+	Burst int     // the calls allowed at once
+}
+
+func (p Policy) String() string { return fmt.Sprintf("%v/%d", p.Rate, p.Burst) }
+
+// the time to refill a token
+func (p Policy) interval() time.Duration { return time.Duration(float64(time.Minute) / p.Rate) }
+
+// the key of the policy of the user for the method
+func UserKey(user, method string) string { return user + ":" + method }
+
+// parse "method:rate/burst,method:rate/burst", the rate is a minute, e.g. "*:120/30,Login:10/5"
+// the method may be of a user, e.g. "uid:1:Login:60/10", see UserKey
+func ParsePolicies(conf string) (map[string]Policy, error) {
+	policies := make(map[string]Policy)
+	if strings.TrimSpace(conf) == "" {
+		return policies, nil
+	}
+	for _, kv := range strings.Split(conf, ",") {
+		kv = strings.TrimSpace(kv)
+		i := strings.LastIndex(kv, ":")
+		if i <= 0 || strings.HasSuffix(kv[:i], ":") {
+			return nil, fmt.Errorf("the policy %q should be method:rate/burst or user:method:rate/burst", kv)
+		}
+		rb := strings.SplitN(kv[i+1:], "/", 2)
+		if len(rb) != 2 {
+			return nil, fmt.Errorf("the policy %q should be method:rate/burst or user:method:rate/burst", kv)
+		}
+		rate, err := strconv.ParseFloat(rb[0], 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("the rate of %q should be a positive number", kv)
+		}
+		burst, err := strconv.Atoi(rb[1])
+		if err != nil || burst < 1 {
+			return nil, fmt.Errorf("the burst of %q should be a positive integer", kv)
+		}
+		policies[kv[:i]] = Policy{Rate: rate, Burst: burst}
+	}
+	return policies, nil
+}
+
+// the calls of a method
+type Metric struct {
+	Allowed int64 `json:"allowed"`
+	Limited int64 `json:"limited"`
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type bucketKey struct{ method, user string }
+
+type Limiter struct {
+	policies  map[string]Policy
+	allowlist map[string]bool // the ips and users not limited
+	buckets   map[bucketKey]*bucket
+	metrics   map[string]*Metric
+	lastGc    time.Time
+	clock     timer.Clock
+	mu        sync.Mutex
+}
+
+// the policies by method, with the Default one
+func New(policies map[string]Policy) *Limiter { return NewWithClock(policies, timer.RealClock) }
+
+func NewWithClock(policies map[string]Policy, clock timer.Clock) *Limiter {
+	if _, ok := policies[Default]; !ok {
+		panic("the default policy is not set")
+	}
+	return &Limiter{
+		policies:  policies,
+		allowlist: make(map[string]bool),
+		buckets:   make(map[bucketKey]*bucket),
+		metrics:   make(map[string]*Metric),
+		lastGc:    clock.Now(),
+		clock:     clock,
+	}
+}
+
+// override the policies, e.g. from the configuration
+func (l *Limiter) SetPolicies(policies map[string]Policy) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for method, p := range policies {
+		l.policies[method] = p
+	}
+}
+
+// the ips or the users not limited
+func (l *Limiter) Allow(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if key = strings.TrimSpace(key); key != "" {
+			l.allowlist[key] = true
+		}
+	}
+}
+
+// the policy of the user for the method, for all the methods, of the method, or the default one
+func (l *Limiter) policy(method, user string) Policy {
+	for _, key := range []string{UserKey(user, method), UserKey(user, Default), method} {
+		if p, ok := l.policies[key]; ok {
+			return p
+		}
+	}
+	return l.policies[Default]
+}
+
+// take a token of the method for the user, or the ip if the user is empty
+// return false and how long to wait if the bucket is empty
+func (l *Limiter) Take(method, ip, user string) (retryAfter time.Duration, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m := l.metrics[method]
+	if m == nil {
+		m = new(Metric)
+		l.metrics[method] = m
+	}
+	if l.allowlist[ip] || (user != "" && l.allowlist[user]) {
+		m.Allowed++
+		return 0, true
+	}
+	if user == "" {
+		user = ip
+	}
+
+	now, p := l.clock.Now(), l.policy(method, user)
+	l.gc(now)
+	key := bucketKey{method, user}
+	b := l.buckets[key]
+	if b == nil {
+		b = &bucket{tokens: float64(p.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(p.Burst), b.tokens+now.Sub(b.last).Minutes()*p.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		m.Limited++
+		return time.Duration((1 - b.tokens) * float64(p.interval())), false
+	}
+	b.tokens--
+	m.Allowed++
+	return 0, true
+}
+
+// delete the full buckets at most once a minute, they are the same as the new ones
+func (l *Limiter) gc(now time.Time) {
+	if now.Sub(l.lastGc) < time.Minute {
+		return
+	}
+	l.lastGc = now
+	for key, b := range l.buckets {
+		p := l.policy(key.method, key.user)
+		if b.tokens+now.Sub(b.last).Minutes()*p.Rate >= float64(p.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// the calls by method, and the number of the buckets
+func (l *Limiter) Metrics() (map[string]Metric, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	metrics := make(map[string]Metric, len(l.metrics))
+	for method, m := range l.metrics {
+		metrics[method] = *m
+	}
+	return metrics, len(l.buckets)
+}
+
+// the metrics as plain maps, for the rpc of the operator
+func (l *Limiter) Stats() map[string]interface{} {
+	metrics, buckets := l.Metrics()
+	methods := make(map[string]interface{}, len(metrics))
+	for method, m := range metrics {
+		methods[method] = map[string]interface{}{"allowed": m.Allowed, "limited": m.Limited}
+	}
+	return map[string]interface{}{"methods": methods, "buckets": buckets}
+}
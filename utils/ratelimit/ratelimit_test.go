@@ -0,0 +1,181 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gogames/go_tetris/timer"
+)
+
+func newTestLimiter() (*Limiter, *timer.FakeClock) {
+	fc := timer.NewFakeClock(time.Unix(1000, 0))
+	return NewWithClock(map[string]Policy{
+		Default: {Rate: 60, Burst: 3},
+		"Login": {Rate: 2, Burst: 1},
+	}, fc), fc
+}
+
+func Test_Burst(t *testing.T) {
+	l, fc := newTestLimiter()
+	for i := 0; i < 3; i++ {
+		if _, ok := l.Take("GetData", "1.1.1.1", ""); !ok {
+			t.Fatalf("the call %d should be allowed in the burst", i)
+		}
+	}
+	retryAfter, ok := l.Take("GetData", "1.1.1.1", "")
+	if ok || retryAfter != time.Second {
+		t.Errorf("the call should be limited for a second, got %v, %v", retryAfter, ok)
+	}
+
+	// a token a second
+	fc.Advance(time.Second)
+	if _, ok := l.Take("GetData", "1.1.1.1", ""); !ok {
+		t.Error("the refilled token should be taken")
+	}
+	if _, ok := l.Take("GetData", "1.1.1.1", ""); ok {
+		t.Error("the bucket should be empty again")
+	}
+
+	// the bucket is not fuller than the burst
+	fc.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		l.Take("GetData", "1.1.1.1", "")
+	}
+	if _, ok := l.Take("GetData", "1.1.1.1", ""); ok {
+		t.Error("the burst should be the capacity of the bucket")
+	}
+}
+
+func Test_Policies(t *testing.T) {
+	l, _ := newTestLimiter()
+	if _, ok := l.Take("Login", "1.1.1.1", ""); !ok {
+		t.Fatal("the first login should be allowed")
+	}
+	if retryAfter, ok := l.Take("Login", "1.1.1.1", ""); ok || retryAfter != 30*time.Second {
+		t.Errorf("the second login should wait 30 seconds, got %v, %v", retryAfter, ok)
+	}
+
+	// the other methods, ips and users have their own buckets
+	if _, ok := l.Take("GetData", "1.1.1.1", ""); !ok {
+		t.Error("the other method should be allowed")
+	}
+	if _, ok := l.Take("Login", "2.2.2.2", ""); !ok {
+		t.Error("the other ip should be allowed")
+	}
+	if _, ok := l.Take("Login", "1.1.1.1", "uid:1"); !ok {
+		t.Error("the user should be limited by uid instead of ip")
+	}
+	if _, ok := l.Take("Login", "3.3.3.3", "uid:1"); ok {
+		t.Error("the user should be limited from any ip")
+	}
+
+	// the allowlist
+	l.Allow("1.1.1.1", " uid:2")
+	for i := 0; i < 10; i++ {
+		if _, ok := l.Take("Login", "1.1.1.1", ""); !ok {
+			t.Fatal("the ip in the allowlist should not be limited")
+		}
+		if _, ok := l.Take("Login", "4.4.4.4", "uid:2"); !ok {
+			t.Fatal("the user in the allowlist should not be limited")
+		}
+	}
+
+	// override
+	l.SetPolicies(map[string]Policy{"Login": {Rate: 60, Burst: 5}})
+	if _, ok := l.Take("Login", "5.5.5.5", ""); !ok {
+		t.Error("the new policy should be used")
+	}
+
+	metrics, _ := l.Metrics()
+	if m := metrics["Login"]; m.Allowed != 24 || m.Limited != 2 {
+		t.Errorf("the metrics of Login should be 24 allowed and 2 limited, got %+v", m)
+	}
+	stats := l.Stats()
+	login := stats["methods"].(map[string]interface{})["Login"].(map[string]interface{})
+	if login["allowed"] != int64(24) || login["limited"] != int64(2) || stats["buckets"] != 5 {
+		t.Errorf("unexpected stats %v", stats)
+	}
+}
+
+func Test_UserPolicies(t *testing.T) {
+	l, fc := newTestLimiter()
+	l.SetPolicies(map[string]Policy{
+		UserKey("uid:1", "Login"):   {Rate: 60, Burst: 3},
+		UserKey("uid:2", Default):   {Rate: 1, Burst: 1},
+		UserKey("1.1.1.1", "Login"): {Rate: 60, Burst: 2},
+	})
+	for i := 0; i < 3; i++ {
+		if _, ok := l.Take("Login", "3.3.3.3", "uid:1"); !ok {
+			t.Fatalf("the login %d of uid:1 should be allowed by its own policy", i)
+		}
+	}
+	if retryAfter, ok := l.Take("Login", "3.3.3.3", "uid:1"); ok || retryAfter != time.Second {
+		t.Errorf("uid:1 should wait a second by its own policy, got %v, %v", retryAfter, ok)
+	}
+	// the others of the method
+	l.Take("Login", "3.3.3.3", "uid:3")
+	if _, ok := l.Take("Login", "3.3.3.3", "uid:3"); ok {
+		t.Error("the other user should be limited by the policy of the method")
+	}
+
+	// the policy of the user for all the methods goes before the one of the method
+	if _, ok := l.Take("GetData", "3.3.3.3", "uid:2"); !ok {
+		t.Fatal("the first call of uid:2 should be allowed")
+	}
+	if retryAfter, ok := l.Take("Login", "3.3.3.3", "uid:2"); !ok {
+		t.Errorf("the bucket of each method is its own, got %v", retryAfter)
+	}
+	if retryAfter, ok := l.Take("GetData", "3.3.3.3", "uid:2"); ok || retryAfter != time.Minute {
+		t.Errorf("uid:2 should wait a minute, got %v, %v", retryAfter, ok)
+	}
+
+	// the ip without a user
+	l.Take("Login", "1.1.1.1", "")
+	if _, ok := l.Take("Login", "1.1.1.1", ""); !ok {
+		t.Error("the ip should be limited by its own policy")
+	}
+
+	// the bucket is full by the policy of the user
+	fc.Advance(time.Minute)
+	l.Take("Login", "4.4.4.4", "")
+	if _, n := l.Metrics(); n != 1 {
+		t.Errorf("the refilled buckets should be deleted, got %d buckets", n)
+	}
+}
+
+func Test_Gc(t *testing.T) {
+	l, fc := newTestLimiter()
+	l.Take("GetData", "1.1.1.1", "")
+	l.Take("Login", "1.1.1.1", "")
+	fc.Advance(time.Minute)
+	l.Take("Login", "2.2.2.2", "")
+	// the bucket of GetData is full, Login of 1.1.1.1 is refilled as well
+	if _, n := l.Metrics(); n != 1 {
+		t.Errorf("the full buckets should be deleted, got %d buckets", n)
+	}
+}
+
+func Test_ParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies("*:120/30, Login:0.5/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policies[Default] != (Policy{120, 30}) || policies["Login"] != (Policy{0.5, 2}) {
+		t.Errorf("unexpected policies %v", policies)
+	}
+	if policies, err := ParsePolicies(""); err != nil || len(policies) != 0 {
+		t.Errorf("the empty configuration should be no policy, got %v, %v", policies, err)
+	}
+	policies, err = ParsePolicies("uid:1:Login:60/10,uid:2:*:1/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policies[UserKey("uid:1", "Login")] != (Policy{60, 10}) || policies[UserKey("uid:2", Default)] != (Policy{1, 1}) {
+		t.Errorf("unexpected policies of the users %v", policies)
+	}
+	for _, conf := range []string{"Login", "Login:1", "Login:0/1", "Login:1/0", ":1/1", "uid:1::1/1", "uid:1:Login:1"} {
+		if _, err := ParsePolicies(conf); err == nil {
+			t.Errorf("the policy %q should be rejected", conf)
+		}
+	}
+}
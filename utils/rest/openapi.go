@@ -0,0 +1,131 @@
+package rest
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/gogames/go_tetris/errcode"
+)
+
+type object map[string]interface{}
+
+// the openapi 3.0 document of the routes, the schemas are from the types of the stub
+func (g *Gateway) OpenAPI() map[string]interface{} {
+	paths := make(map[string]object)
+	for _, rt := range g.routes {
+		if paths[rt.Path] == nil {
+			paths[rt.Path] = make(object)
+		}
+		paths[rt.Path][strings.ToLower(rt.Method)] = rt.operation()
+	}
+	doc := object{
+		"openapi": "3.0.3",
+		"info":    object{"title": g.Title, "version": g.Version},
+		"paths":   paths,
+		"components": object{
+			"schemas": object{"Error": schema(reflect.TypeOf(errcode.Response{}))},
+			"securitySchemes": object{
+				"session": object{"type": "apiKey", "in": "header", "name": SessionHeader},
+			},
+		},
+	}
+	if g.Base != "" {
+		doc["servers"] = []object{{"url": g.Base}}
+	}
+	return doc
+}
+
+func (rt *route) operation() object {
+	results := make(object)
+	for i, name := range rt.Results {
+		results[name] = schema(rt.method.Type().Out(i))
+	}
+	op := object{
+		"operationId": rt.Stub,
+		"summary":     rt.Summary,
+		"responses": object{
+			"200": object{
+				"description": "the results",
+				"content":     jsonContent(object{"type": "object", "properties": results}),
+			},
+			"default": object{
+				"description": "the error, see the package errcode",
+				"content":     jsonContent(object{"$ref": "#/components/schemas/Error"}),
+			},
+		},
+	}
+	if rt.Tag != "" {
+		op["tags"] = []string{rt.Tag}
+	}
+	if rt.session {
+		op["security"] = []object{{"session": []string{}}}
+	}
+
+	var params []object
+	props, required := make(object), []string{}
+	for _, p := range rt.params {
+		if p.in == inBody {
+			props[p.name] = schema(p.typ)
+			if !p.optional {
+				required = append(required, p.name)
+			}
+			continue
+		}
+		params = append(params, object{"name": p.name, "in": p.in, "required": !p.optional, "schema": schema(p.typ)})
+	}
+	if params != nil {
+		op["parameters"] = params
+	}
+	if len(props) > 0 {
+		body := object{"type": "object", "properties": props}
+		if len(required) > 0 {
+			body["required"] = required
+		}
+		op["requestBody"] = object{"required": len(required) > 0, "content": jsonContent(body)}
+	}
+	return op
+}
+
+func jsonContent(schema object) object {
+	return object{"application/json": object{"schema": schema}}
+}
+
+// the json schema of a type, as encoding/json marshals it
+func schema(t reflect.Type) object {
+	switch t.Kind() {
+	case reflect.Bool:
+		return object{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return object{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return object{"type": "number"}
+	case reflect.String:
+		return object{"type": "string"}
+	case reflect.Ptr:
+		return schema(t.Elem())
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return object{"type": "string", "format": "byte"}
+		}
+		return object{"type": "array", "items": schema(t.Elem())}
+	case reflect.Map:
+		return object{"type": "object", "additionalProperties": schema(t.Elem())}
+	case reflect.Struct:
+		props := make(object)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if f.PkgPath != "" || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = schema(f.Type)
+		}
+		return object{"type": "object", "properties": props}
+	}
+	// interface{}, any value
+	return object{}
+}
@@ -0,0 +1,276 @@
+/*
+rest/json gateway of an rpc stub, the routes map onto the methods of the stub
+
+	POST /tables/{tid}/kick {"uid": 2, "ban": true}  ->  stub.Kick(tid, uid, ban, sessId)
+
+the params are in the path, the query of GET and DELETE, or the json body of the others
+the session id is the X-Session-Id header, the last param of the methods taking one more
+the results are a json object by their names, the errors are errcode.Response with an http status
+the openapi document is generated from the routes and the types of the stub, GET /openapi.json
+*/
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gogames/go_tetris/errcode"
+	"github.com/gogames/go_tetris/utils"
+	"github.com/hprose/hprose-go/hprose"
+)
+
+const (
+	SessionHeader = "X-Session-Id"
+	DocPath       = "/openapi.json"
+	maxBody       = 1 << 20 // the avatar is 256KB at most, in base64
+)
+
+// where a param is
+const (
+	inPath  = "path"
+	inQuery = "query"
+	inBody  = "body"
+)
+
+type Route struct {
+	Method  string   // GET, POST, PUT or DELETE
+	Path    string   // e.g. /tables/{tid}, the first matched route is used
+	Stub    string   // the method of the stub
+	Params  []string // the names of the params without the session id, optional if ends with ?
+	Results []string // the names of the results
+	Tag     string   // the resource, to group the routes in the document
+	Summary string
+}
+
+type param struct {
+	name     string
+	in       string
+	typ      reflect.Type
+	optional bool
+}
+
+type route struct {
+	Route
+	segments []string
+	method   reflect.Value
+	params   []param
+	session  bool // the last param of the method is the session id
+}
+
+type Gateway struct {
+	Title, Version string
+	Base           string // the path the gateway is served at, in the document
+
+	// called before the method with its params, e.g. to check the session and the rate limit
+	// the same as OnBeforeInvoke of hprose, panic to reject the request
+	Before func(name string, params []reflect.Value, ctx interface{})
+
+	routes []*route
+}
+
+// panic if a route does not match the stub, it is a bug
+func NewGateway(stub interface{}, routes []Route) *Gateway {
+	g := &Gateway{Title: "api", Version: "1"}
+	v, stubs := reflect.ValueOf(stub), make(map[string]bool)
+	for _, r := range routes {
+		if stubs[r.Stub] {
+			panic(fmt.Sprintf("the method %s is routed twice", r.Stub))
+		}
+		stubs[r.Stub] = true
+		g.routes = append(g.routes, newRoute(v, r))
+	}
+	return g
+}
+
+func newRoute(stub reflect.Value, r Route) *route {
+	rt := &route{Route: r, segments: split(r.Path), method: stub.MethodByName(r.Stub)}
+	if !rt.method.IsValid() {
+		panic(fmt.Sprintf("the stub has no method %s", r.Stub))
+	}
+	t := rt.method.Type()
+	switch t.NumIn() {
+	case len(r.Params):
+	case len(r.Params) + 1:
+		if t.In(t.NumIn()-1).Kind() != reflect.String {
+			panic(fmt.Sprintf("the session id of %s should be a string", r.Stub))
+		}
+		rt.session = true
+	default:
+		panic(fmt.Sprintf("%s has %d params, the route has %d", r.Stub, t.NumIn(), len(r.Params)))
+	}
+	if t.NumOut() != len(r.Results) {
+		panic(fmt.Sprintf("%s has %d results, the route has %d", r.Stub, t.NumOut(), len(r.Results)))
+	}
+
+	vars := make(map[string]bool)
+	for _, s := range rt.segments {
+		if isVar(s) {
+			vars[s[1:len(s)-1]] = true
+		}
+	}
+	for i, name := range r.Params {
+		p := param{name: strings.TrimSuffix(name, "?"), typ: t.In(i), optional: strings.HasSuffix(name, "?")}
+		switch {
+		case vars[p.name]:
+			p.in, p.optional = inPath, false
+			delete(vars, p.name)
+		case r.Method == "GET" || r.Method == "DELETE":
+			p.in = inQuery
+		default:
+			p.in = inBody
+		}
+		rt.params = append(rt.params, p)
+	}
+	for name := range vars {
+		panic(fmt.Sprintf("%s of the path %s is not a param of %s", name, r.Path, r.Stub))
+	}
+	return rt
+}
+
+func split(path string) []string { return strings.Split(strings.Trim(path, "/"), "/") }
+
+func isVar(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// the route and the values of the path params
+func (g *Gateway) match(method, path string) (*route, map[string]string) {
+	segments := split(path)
+next:
+	for _, rt := range g.routes {
+		if rt.Method != method || len(rt.segments) != len(segments) {
+			continue
+		}
+		vars := make(map[string]string)
+		for i, s := range rt.segments {
+			switch {
+			case isVar(s):
+				vars[s[1:len(s)-1]] = segments[i]
+			case s != segments[i]:
+				continue next
+			}
+		}
+		return rt, vars
+	}
+	return nil, nil
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Expose-Headers", "Retry-After")
+	if r.Method == "OPTIONS" {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept-Language, "+SessionHeader)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method == "GET" && r.URL.Path == DocPath {
+		writeJson(w, http.StatusOK, g.OpenAPI())
+		return
+	}
+
+	// the same context as hprose, for the helpers of utils
+	ctx := &hprose.HttpContext{Request: r, Response: w}
+	defer func() {
+		if e := recover(); e != nil {
+			err := errcode.Parse(fmt.Sprint(e))
+			writeJson(w, Status(err.Code), err.In(utils.RequestLanguage(ctx)))
+		}
+	}()
+	rt, vars := g.match(r.Method, r.URL.Path)
+	if rt == nil {
+		panic(errcode.New(errcode.UnknownRoute, r.Method, r.URL.Path))
+	}
+	args := rt.args(r, vars)
+	if g.Before != nil {
+		g.Before(rt.Stub, args, ctx)
+	}
+	out := rt.method.Call(args)
+	res := make(map[string]interface{}, len(out))
+	for i, v := range out {
+		res[rt.Results[i]] = v.Interface()
+	}
+	writeJson(w, http.StatusOK, res)
+}
+
+// the params of the method, panic with IncorrectParam if one is missing or not valid
+func (rt *route) args(r *http.Request, vars map[string]string) []reflect.Value {
+	body := make(map[string]json.RawMessage)
+	if r.Method != "GET" && r.Method != "DELETE" && r.ContentLength != 0 {
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxBody)).Decode(&body); err != nil {
+			panic(errcode.New(errcode.IncorrectParam, "body"))
+		}
+	}
+	query := r.URL.Query()
+	args := make([]reflect.Value, 0, len(rt.params)+1)
+	for _, p := range rt.params {
+		var raw []byte
+		switch p.in {
+		case inPath:
+			raw = text(p.typ, vars[p.name])
+		case inQuery:
+			if vals, ok := query[p.name]; ok {
+				raw = text(p.typ, vals[0])
+			}
+		case inBody:
+			raw = body[p.name]
+		}
+		v := reflect.New(p.typ)
+		if raw == nil && !p.optional {
+			panic(errcode.New(errcode.IncorrectParam, p.name))
+		}
+		if raw != nil {
+			if err := json.Unmarshal(raw, v.Interface()); err != nil {
+				panic(errcode.New(errcode.IncorrectParam, p.name))
+			}
+		}
+		args = append(args, v.Elem())
+	}
+	if rt.session {
+		args = append(args, reflect.ValueOf(r.Header.Get(SessionHeader)))
+	}
+	return args
+}
+
+// the json of a param in the path or the query, the strings are not quoted there
+func text(t reflect.Type, s string) []byte {
+	if t.Kind() == reflect.String {
+		b, _ := json.Marshal(s)
+		return b
+	}
+	return []byte(s)
+}
+
+// the http status of the error
+func Status(code errcode.Code) int {
+	switch code {
+	case errcode.Unknown:
+		return http.StatusInternalServerError
+	case errcode.CreateSessionFirst, errcode.NotLoggedIn:
+		return http.StatusUnauthorized
+	case errcode.Banned, errcode.PrivateTable, errcode.NotTableOwner:
+		return http.StatusForbidden
+	case errcode.UnknownRoute, errcode.UserNotExist, errcode.TableNotExist, errcode.TableNotFound:
+		return http.StatusNotFound
+	case errcode.TooFrequent:
+		return http.StatusTooManyRequests
+	case errcode.ServerClosing, errcode.NoWorkingGameServer:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadRequest
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		b, _ = json.Marshal(errcode.New(errcode.Unknown, err).In(errcode.Default))
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(b)
+}
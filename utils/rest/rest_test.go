@@ -0,0 +1,175 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gogames/go_tetris/errcode"
+)
+
+type testStub struct{}
+
+func (testStub) Count() int { return 3 }
+
+func (testStub) Kick(tid, uid int, ban bool, sessId string) (string, int) {
+	if tid == 0 {
+		panic(errcode.New(errcode.TableNotExist, tid))
+	}
+	if ban {
+		return sessId, tid + uid
+	}
+	return sessId, tid
+}
+
+func (testStub) Find(name string, tags []string, sessId string) []string {
+	return append([]string{name}, tags...)
+}
+
+var testRoutes = []Route{
+	{Method: "GET", Path: "/count", Stub: "Count", Results: []string{"count"}},
+	{Method: "POST", Path: "/tables/{tid}/kick", Stub: "Kick", Params: []string{"tid", "uid", "ban?"}, Results: []string{"session", "sum"}, Tag: "tables"},
+	{Method: "GET", Path: "/find/{name}", Stub: "Find", Params: []string{"name", "tags?"}, Results: []string{"names"}},
+}
+
+func do(g *Gateway, method, path, body string, header map[string]string) (int, map[string]interface{}) {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+	var res map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	return w.Code, res
+}
+
+func Test_Gateway(t *testing.T) {
+	g := NewGateway(testStub{}, testRoutes)
+	var before []string
+	g.Before = func(name string, params []reflect.Value, ctx interface{}) {
+		before = append(before, name)
+		if l := len(params); l > 0 && params[l-1].String() == "closed" {
+			panic(errcode.New(errcode.ServerClosing))
+		}
+	}
+
+	if status, res := do(g, "GET", "/count", "", nil); status != 200 || res["count"] != 3.0 {
+		t.Errorf("unexpected count %d %v", status, res)
+	}
+	status, res := do(g, "POST", "/tables/2/kick", `{"uid": 5, "ban": true}`, map[string]string{SessionHeader: "s1"})
+	if status != 200 || res["session"] != "s1" || res["sum"] != 7.0 {
+		t.Errorf("unexpected kick %d %v", status, res)
+	}
+	// the optional param is the zero value
+	if _, res := do(g, "POST", "/tables/2/kick", `{"uid": 5}`, nil); res["sum"] != 2.0 {
+		t.Errorf("the ban should be false, got %v", res)
+	}
+	if _, res := do(g, "GET", "/find/a%20b?tags=[\"c\"]", "", nil); !reflect.DeepEqual(res["names"], []interface{}{"a b", "c"}) {
+		t.Errorf("unexpected names %v", res)
+	}
+	if !reflect.DeepEqual(before, []string{"Count", "Kick", "Kick", "Find"}) {
+		t.Errorf("Before should be called for each method, got %v", before)
+	}
+}
+
+func Test_GatewayErrors(t *testing.T) {
+	g := NewGateway(testStub{}, testRoutes)
+	g.Before = func(name string, params []reflect.Value, ctx interface{}) {
+		if l := len(params); l > 0 && params[l-1].String() == "closed" {
+			panic(errcode.New(errcode.ServerClosing))
+		}
+	}
+	for _, c := range []struct {
+		method, path, body, sessId string
+		status                     int
+		code                       errcode.Code
+	}{
+		{"GET", "/tables/2/kick", "", "", 404, errcode.UnknownRoute},
+		{"POST", "/tables/x/kick", `{"uid": 1}`, "", 400, errcode.IncorrectParam},
+		{"POST", "/tables/2/kick", `{}`, "", 400, errcode.IncorrectParam},
+		{"POST", "/tables/2/kick", `{"uid": "1"}`, "", 400, errcode.IncorrectParam},
+		{"POST", "/tables/2/kick", `{`, "", 400, errcode.IncorrectParam},
+		{"POST", "/tables/0/kick", `{"uid": 1}`, "", 404, errcode.TableNotExist},
+		{"POST", "/tables/2/kick", `{"uid": 1}`, "closed", 503, errcode.ServerClosing},
+	} {
+		status, res := do(g, c.method, c.path, c.body, map[string]string{SessionHeader: c.sessId, "Accept-Language": "en"})
+		if status != c.status || res["code"] != float64(c.code) {
+			t.Errorf("%s %s %s should be %d %d, got %d %v", c.method, c.path, c.body, c.status, c.code, status, res)
+		}
+	}
+	// in the language of the request
+	_, res := do(g, "POST", "/tables/0/kick", `{"uid": 1}`, map[string]string{"Accept-Language": "en"})
+	if res["message"] != "the table 0 does not exist, please join another one" {
+		t.Errorf("the error should be in en, got %v", res["message"])
+	}
+}
+
+func Test_NewGateway(t *testing.T) {
+	for _, r := range []Route{
+		{Method: "GET", Path: "/x", Stub: "None"},
+		{Method: "GET", Path: "/count", Stub: "Count"},
+		{Method: "POST", Path: "/kick", Stub: "Kick", Params: []string{"tid"}, Results: []string{"session", "sum"}},
+		{Method: "GET", Path: "/find/{id}", Stub: "Find", Params: []string{"name", "tags"}, Results: []string{"names"}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("the route %v does not match the stub", r)
+				}
+			}()
+			NewGateway(testStub{}, []Route{r})
+		}()
+	}
+}
+
+func Test_OpenAPI(t *testing.T) {
+	g := NewGateway(testStub{}, testRoutes)
+	g.Base = "/api"
+	status, doc := do(g, "GET", DocPath, "", nil)
+	if status != 200 || doc["openapi"] != "3.0.3" {
+		t.Fatalf("unexpected document %d %v", status, doc)
+	}
+	b, _ := json.Marshal(doc["paths"].(map[string]interface{})["/tables/{tid}/kick"])
+	var kick struct {
+		Post struct {
+			OperationId string
+			Tags        []string
+			Security    []map[string][]string
+			Parameters  []struct {
+				Name, In string
+				Required bool
+				Schema   map[string]string
+			}
+			RequestBody struct {
+				Content map[string]struct {
+					Schema struct {
+						Properties map[string]map[string]string
+						Required   []string
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(b, &kick); err != nil {
+		t.Fatal(err)
+	}
+	op := kick.Post
+	if op.OperationId != "Kick" || op.Tags[0] != "tables" || op.Security[0]["session"] == nil {
+		t.Errorf("unexpected operation %s", b)
+	}
+	if len(op.Parameters) != 1 || op.Parameters[0].Name != "tid" || op.Parameters[0].In != "path" || op.Parameters[0].Schema["type"] != "integer" {
+		t.Errorf("unexpected parameters %s", b)
+	}
+	body := op.RequestBody.Content["application/json"].Schema
+	if body.Properties["ban"]["type"] != "boolean" || !reflect.DeepEqual(body.Required, []string{"uid"}) {
+		t.Errorf("unexpected body %s", b)
+	}
+
+	if s := schema(reflect.TypeOf([]map[string][]byte{})); !reflect.DeepEqual(s, object{"type": "array", "items": object{
+		"type": "object", "additionalProperties": object{"type": "string", "format": "byte"}}}) {
+		t.Errorf("unexpected schema %v", s)
+	}
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/gogames/go_tetris/errcode"
+)
+
+var errResumeToken = errcode.New(errcode.ResumeToken)
+
+// the resume token proves the seat of a session, signed by the token key
+// a reloaded client takes the session back with it
+// payload.keyId.signature, the payload is base64 of resume|uid|tid|sessionId
+func GenerateResumeToken(uid, tid int, sessId string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("resume|%d|%d|%s", uid, tid, sessId)))
+	id, signature := sign(payload)
+	return payload + "." + id + "." + signature
+}
+
+func ParseResumeToken(token string) (uid, tid int, sessId string, err error) {
+	vals := strings.Split(token, ".")
+	if len(vals) != 3 || !verify(vals[1], vals[0], vals[2]) {
+		return 0, 0, "", errResumeToken
+	}
+	b, err := base64.RawURLEncoding.DecodeString(vals[0])
+	if err != nil {
+		return 0, 0, "", errResumeToken
+	}
+	if _, err := fmt.Sscanf(string(b), "resume|%d|%d|%s", &uid, &tid, &sessId); err != nil {
+		return 0, 0, "", errResumeToken
+	}
+	return uid, tid, sessId, nil
+}
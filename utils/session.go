@@ -5,6 +5,8 @@ import (
 	"reflect"
 	"sync"
 	"time"
+
+	"github.com/gogames/go_tetris/timer"
 )
 
 const (
@@ -20,9 +22,15 @@ type sessionStore struct {
 	garbageChanBuffer int
 	enableGarbageChan bool
 	GarbageSession    chan *session
+	clock             timer.Clock
 }
 
 func NewSessionStore(expires ...int64) *sessionStore {
+	return NewSessionStoreWithClock(timer.RealClock, expires...)
+}
+
+// the sessions expire by the clock
+func NewSessionStoreWithClock(clock timer.Clock, expires ...int64) *sessionStore {
 	var expire int64
 	if l := len(expires); l > 0 {
 		expire = expires[l-1]
@@ -32,6 +40,7 @@ func NewSessionStore(expires ...int64) *sessionStore {
 	ss := &sessionStore{
 		sess:           make(map[string]*session),
 		expireInSecond: expire,
+		clock:          clock,
 	}
 	return ss.init()
 }
@@ -51,7 +60,7 @@ func (ss *sessionStore) Init(sess map[string]map[string]interface{}) {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 	for sessId, ses := range sess {
-		ss.sess[sessId] = newSession()
+		ss.sess[sessId] = newSession(ss.clock)
 		for key, val := range ses {
 			ss.sess[sessId].set(key, val)
 		}
@@ -69,7 +78,7 @@ func (ss *sessionStore) gc() {
 	getExpire := func() []string {
 		ss.mu.RLock()
 		defer ss.mu.RUnlock()
-		tNow := time.Now().Unix()
+		tNow := ss.clock.Now().Unix()
 		sss := make([]string, 0)
 		for sessId, v := range ss.sess {
 			if tNow-v.updated > ss.expireInSecond {
@@ -83,7 +92,7 @@ func (ss *sessionStore) gc() {
 		frequency = time.Second * time.Duration(ss.expireInSecond)
 	}
 	for {
-		time.Sleep(frequency)
+		ss.clock.Sleep(frequency)
 		ss.delSession(getExpire()...)
 	}
 }
@@ -112,6 +121,10 @@ func (ss *sessionStore) delSession(sessionIds ...string) {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 	for _, sessionId := range sessionIds {
+		// may be already deleted
+		if ss.sess[sessionId] == nil {
+			continue
+		}
 		if ss.enableGarbageChan {
 			ss.GarbageSession <- ss.sess[sessionId]
 		}
@@ -127,10 +140,31 @@ func (ss *sessionStore) CreateSession() string {
 	}
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
-	ss.sess[sessId] = newSession()
+	ss.sess[sessId] = newSession(ss.clock)
 	return sessId
 }
 
+// move the session to a new session id, the old id is deleted without being collected
+// return empty string if the session does not exist
+func (ss *sessionStore) RebindSession(sessId string) string {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	sess := ss.sess[sessId]
+	if sess == nil {
+		return ""
+	}
+	var newId = ""
+	for newId == "" || ss.sess[newId] != nil {
+		newId = ss.generateSessionId()
+	}
+	delete(ss.sess, sessId)
+	ss.sess[newId] = sess
+	sess.mu.Lock()
+	sess.updated = ss.clock.Now().Unix()
+	sess.mu.Unlock()
+	return newId
+}
+
 // get all session to store in db
 func (ss *sessionStore) GetAllSession() map[string]map[string]interface{} {
 	ss.mu.RLock()
@@ -174,6 +208,19 @@ func (ss *sessionStore) DelSession(sessId string) {
 	ss.delSession(sessId)
 }
 
+// the session ids whose value of the key equals to val
+func (ss *sessionStore) SessIdsOf(key string, val interface{}) []string {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	sessIds := make([]string, 0)
+	for sessId, sess := range ss.sess {
+		if sess.Get(key) == val {
+			sessIds = append(sessIds, sessId)
+		}
+	}
+	return sessIds
+}
+
 // get data from session
 func (ss *sessionStore) GetSession(key string, sessId string) interface{} {
 	if !ss.IsSessIdExist(sessId) {
@@ -203,11 +250,13 @@ type session struct {
 	updated int64
 	vals    map[string]interface{}
 	mu      sync.RWMutex
+	clock   timer.Clock
 }
 
-func newSession() *session {
+func newSession(clock timer.Clock) *session {
 	return &session{
-		updated: time.Now().Unix(),
+		clock:   clock,
+		updated: clock.Now().Unix(),
 		vals:    make(map[string]interface{}),
 	}
 }
@@ -216,7 +265,7 @@ func (s *session) set(key string, val interface{}) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.vals[key] = val
-	s.updated = time.Now().Unix()
+	s.updated = s.clock.Now().Unix()
 }
 
 func (s *session) Get(key string) interface{} {
@@ -229,5 +278,5 @@ func (s *session) del(key string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	delete(s.vals, key)
-	s.updated = time.Now().Unix()
+	s.updated = s.clock.Now().Unix()
 }
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gogames/go_tetris/timer"
+)
+
+func Test_SessionExpire(t *testing.T) {
+	fc := timer.NewFakeClock(time.Unix(0, 0))
+	ss := NewSessionStoreWithClock(fc, 10)
+	ss.EnableGarbageChan(1)
+	sessId := ss.CreateSession()
+
+	// gc sleeps
+	fc.BlockUntil(1)
+	fc.Advance(10 * time.Second)
+	fc.BlockUntil(1)
+	if !ss.IsSessIdExist(sessId) {
+		t.Fatal("the session should not expire in 10 seconds")
+	}
+
+	fc.Advance(10 * time.Second)
+	select {
+	case <-ss.GarbageSession:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the session should expire")
+	}
+	if ss.IsSessIdExist(sessId) {
+		t.Error("the expired session should be deleted")
+	}
+}
+
+func Test_SessIdsOf(t *testing.T) {
+	ss := NewSessionStoreWithClock(timer.NewFakeClock(time.Unix(0, 0)))
+	ss.EnableGarbageChan(4)
+	a, b, c := ss.CreateSession(), ss.CreateSession(), ss.CreateSession()
+	ss.SetSession("uid", 1, a)
+	ss.SetSession("uid", 1, b)
+	ss.SetSession("uid", 2, c)
+	if ids := ss.SessIdsOf("uid", 1); len(ids) != 2 {
+		t.Fatalf("user 1 should have 2 sessions, got %v", ids)
+	}
+
+	// delete twice, only one garbage
+	ss.DelSession(a)
+	ss.DelSession(a)
+	if len(ss.GarbageSession) != 1 {
+		t.Errorf("the deleted session should be collected once, got %d", len(ss.GarbageSession))
+	}
+	if ids := ss.SessIdsOf("uid", 1); len(ids) != 1 || ids[0] != b {
+		t.Errorf("user 1 should have the session %s, got %v", b, ids)
+	}
+}
+
+func Test_RebindSession(t *testing.T) {
+	ss := NewSessionStoreWithClock(timer.NewFakeClock(time.Unix(0, 0)))
+	ss.EnableGarbageChan(1)
+	old := ss.CreateSession()
+	ss.SetSession("uid", 1, old)
+
+	sessId := ss.RebindSession(old)
+	if sessId == "" || sessId == old {
+		t.Fatalf("the session should be moved to a new id, got %q", sessId)
+	}
+	if ss.IsSessIdExist(old) {
+		t.Error("the old session id should be deleted")
+	}
+	if ss.GetSession("uid", sessId) != 1 {
+		t.Error("the values should be kept")
+	}
+	if len(ss.GarbageSession) != 0 {
+		t.Error("the rebound session should not be collected")
+	}
+	if ss.RebindSession(old) != "" {
+		t.Error("can not rebind a deleted session")
+	}
+}
@@ -1,72 +1,151 @@
 package utils
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
-	"strconv"
 	"strings"
+	"time"
 
-	"github.com/xxtea/xxtea-go/xxtea"
+	"github.com/gogames/go_tetris/errcode"
+	"github.com/gogames/go_tetris/timer"
 )
 
-var tokenKey []byte
+// the keys to sign the tokens, the first one signs and all of them verify
+// to rotate the keys, add a new key in front, and remove the old one after its tokens expire
+type signKey struct {
+	id  string
+	key []byte
+}
+
+var tokenKeys []signKey
 
-func SetTokenKey(key []byte) {
-	tokenKey = key
+// set the keys from the configuration, "id:key,id:key"
+func SetTokenKeys(conf string) error {
+	keys, err := parseKeys(conf, "token key")
+	if err != nil {
+		return err
+	}
+	tokenKeys = keys
+	return nil
 }
 
-const errCantGenerateToken = "can not generate token for user %s, %d"
+// parse "id:key,id:key" in order, the ids are unique and do not contain "."
+func parseKeys(conf, name string) ([]signKey, error) {
+	keys := make([]signKey, 0)
+	ids := make(map[string]bool)
+	for _, kv := range strings.Split(conf, ",") {
+		vals := strings.SplitN(strings.TrimSpace(kv), ":", 2)
+		if len(vals) != 2 || vals[0] == "" || vals[1] == "" || strings.Contains(vals[0], ".") {
+			return nil, fmt.Errorf("the %s %q should be id:key", name, kv)
+		}
+		if ids[vals[0]] {
+			return nil, fmt.Errorf("the %s id %s is duplicated", name, vals[0])
+		}
+		ids[vals[0]] = true
+		keys = append(keys, signKey{vals[0], []byte(vals[1])})
+	}
+	return keys, nil
+}
 
-func GenerateToken(uid int, nickname string, isApply bool, isOb bool, tid int) (string, error) {
-	var token string
-	// uid|nickname
-	// uid|nickname|isObserver|tableId|isTournament
-	if isApply {
-		token = fmt.Sprintf("%d|%s", uid, nickname)
-	} else {
-		token = fmt.Sprintf("%d|%s|%v|%d|%v", uid, nickname, isOb, tid, tid >= 1e5)
+// sign the payload by the current key, return the key id and the signature
+func sign(payload string) (string, string) {
+	if len(tokenKeys) == 0 {
+		panic("the token keys are not set")
 	}
-	b := xxtea.Encrypt([]byte(token), tokenKey)
-	if b == nil {
-		return "", fmt.Errorf(errCantGenerateToken, nickname, uid)
+	return tokenKeys[0].id, signBy(tokenKeys[0].key, payload)
+}
+
+func signBy(key []byte, payload string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify the signature by the key of the id
+func verify(id, payload, signature string) bool {
+	for _, k := range tokenKeys {
+		if k.id == id {
+			return hmac.Equal([]byte(signature), []byte(signBy(k.key, payload)))
+		}
 	}
-	return base64.StdEncoding.EncodeToString(b), nil
+	return false
 }
 
-const errTokenError = "the token %s is incorrect"
+// the clock of the tokens, a fake one in tests
+var tokenClock timer.Clock = timer.RealClock
+
+// the join token is valid for a while after the auth server issues it, and only once
+const tokenTTL = 60 * time.Second
 
-func ParseToken(token string) (uid int, nickname string, isApply bool, isOb bool, isTournament bool, tid int, err error) {
-	b, err := base64.StdEncoding.DecodeString(token)
+var (
+	errTokenError   = errcode.New(errcode.TokenError)
+	errTokenExpired = errcode.New(errcode.TokenExpired)
+	errTokenAud     = errcode.New(errcode.TokenAud)
+)
+
+// the join token from the auth server to the game server
+type Token struct {
+	Uid          int    `json:"uid"`
+	Nickname     string `json:"nick"`
+	IsApply      bool   `json:"apply,omitempty"` // apply for tournament
+	IsOb         bool   `json:"ob,omitempty"`
+	Tid          int    `json:"tid"`
+	IsTournament bool   `json:"tour,omitempty"`
+	Aud          string `json:"aud"` // the host of the game server
+	Iat          int64  `json:"iat"`
+	Exp          int64  `json:"exp"`
+	Nonce        string `json:"nonce"` // the game server accepts a nonce once, see NonceStore
+}
+
+const errCantGenerateToken = "can not generate token for user %s, %d: %v"
+
+// payload.keyId.signature, the payload is base64 of the json of Token
+func GenerateToken(uid int, nickname string, isApply bool, isOb bool, tid int, aud string) (string, error) {
+	now := tokenClock.Now()
+	t := Token{
+		Uid:      uid,
+		Nickname: nickname,
+		IsApply:  isApply,
+		Aud:      aud,
+		Iat:      now.Unix(),
+		Exp:      now.Add(tokenTTL).Unix(),
+		Nonce:    RandString(16),
+	}
+	if !isApply {
+		t.IsOb, t.Tid, t.IsTournament = isOb, tid, tid >= 1e5
+	}
+	b, err := json.Marshal(t)
 	if err != nil {
-		return
+		return "", fmt.Errorf(errCantGenerateToken, nickname, uid, err)
 	}
-	token = string(xxtea.Decrypt(b, tokenKey))
-	vals := strings.Split(token, "|")
-	switch len(vals) {
-	case 5:
-		// join a normal game
-		// uid
-		uid, err = strconv.Atoi(vals[0])
-		if err != nil {
-			return
-		}
-		// nickname
-		nickname = vals[1]
-		// isOb
-		isOb = vals[2] == "true"
-		// tid
-		tid, err = strconv.Atoi(vals[3])
-		// isTournament
-		isTournament = vals[4] == "true"
-	case 2:
-		// apply for tournament
-		isApply = true
-		// nickname
-		nickname = vals[1]
-		// uid
-		uid, err = strconv.Atoi(vals[0])
-	default:
-		err = fmt.Errorf(errTokenError, token)
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	id, signature := sign(payload)
+	return payload + "." + id + "." + signature, nil
+}
+
+// verify the signature, the expiry and the audience of the token
+// the caller checks the nonce
+func ParseToken(token, aud string) (*Token, error) {
+	vals := strings.Split(token, ".")
+	if len(vals) != 3 || !verify(vals[1], vals[0], vals[2]) {
+		return nil, errTokenError
+	}
+	b, err := base64.RawURLEncoding.DecodeString(vals[0])
+	if err != nil {
+		return nil, errTokenError
+	}
+	t := new(Token)
+	if err := json.Unmarshal(b, t); err != nil || t.Nonce == "" {
+		return nil, errTokenError
+	}
+	if now := tokenClock.Now().Unix(); now > t.Exp || now < t.Iat-int64(tokenTTL/time.Second) {
+		return nil, errTokenExpired
+	}
+	if t.Aud != aud {
+		return nil, errTokenAud
 	}
-	return
+	return t, nil
 }
@@ -1,37 +1,140 @@
 package utils
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogames/go_tetris/timer"
+)
+
+const testAud = "10.0.0.1:8080"
 
 func Test_Token(t *testing.T) {
-	token, err := GenerateToken(10, "sbChao", true, false, 20)
+	if err := SetTokenKeys("1:token key"); err != nil {
+		t.Fatal(err)
+	}
+	token, err := GenerateToken(10, "sbChao", false, true, 20, testAud)
 	if err != nil {
-		t.Error(err)
-	} else {
-		t.Log("the token is ", token)
+		t.Fatal(err)
 	}
-
-	uid, nickname, isApply, isOb, isTournament, tid, err := ParseToken(token)
+	tk, err := ParseToken(token, testAud)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	if tk.Uid != 10 {
+		t.Errorf("the uid should be 10, got %v", tk.Uid)
+	}
+	if tk.Nickname != "sbChao" {
+		t.Errorf("the nickname should not be %v", tk.Nickname)
+	}
+	if tk.IsApply || !tk.IsOb || tk.Tid != 20 || tk.IsTournament {
+		t.Errorf("it should be observing the normal table 20, got %+v", tk)
+	}
+	if tk.Nonce == "" {
+		t.Error("the token should have a nonce")
 	}
 
-	if uid != 10 {
-		t.Errorf("the uid should be %v", uid)
+	// apply for tournament
+	token, _ = GenerateToken(10, "sbChao", true, false, 20, testAud)
+	if tk, err := ParseToken(token, testAud); err != nil || !tk.IsApply || tk.Tid != 0 {
+		t.Errorf("it should be applying for tournament, got %+v, %v", tk, err)
 	}
-	if nickname != "sbChao" {
-		t.Errorf("the nicnname should not be %v", nickname)
+
+	// another game server
+	if _, err := ParseToken(token, "10.0.0.2:8080"); err != errTokenAud {
+		t.Errorf("the token of another game server should be rejected, got %v", err)
 	}
-	if !isApply {
-		t.Error("it should be applying for tournament")
 
-		if tid != 20 {
-			t.Errorf("the tid should be %v", tid)
-		}
-		if isOb {
-			t.Error("it should be observing a game")
+	// the changed payload
+	vals := strings.Split(token, ".")
+	other, _ := GenerateToken(11, "sbChao", true, false, 20, testAud)
+	if _, err := ParseToken(strings.Split(other, ".")[0]+"."+vals[1]+"."+vals[2], testAud); err != errTokenError {
+		t.Errorf("the changed payload should be rejected, got %v", err)
+	}
+}
+
+func Test_TokenExpiry(t *testing.T) {
+	fc := timer.NewFakeClock(time.Unix(1000, 0))
+	tokenClock = fc
+	defer func() { tokenClock = timer.RealClock }()
+	SetTokenKeys("1:token key")
+
+	token, _ := GenerateToken(10, "sbChao", false, false, 20, testAud)
+	fc.Advance(tokenTTL)
+	if _, err := ParseToken(token, testAud); err != nil {
+		t.Errorf("the token should be valid until it expires, got %v", err)
+	}
+	fc.Advance(time.Second)
+	if _, err := ParseToken(token, testAud); err != errTokenExpired {
+		t.Errorf("the token should expire, got %v", err)
+	}
+}
+
+func Test_TokenKeyRotation(t *testing.T) {
+	SetTokenKeys("1:old key")
+	old, _ := GenerateToken(10, "sbChao", false, false, 20, testAud)
+
+	// the new key signs, the old one still verifies
+	if err := SetTokenKeys("2:new key, 1:old key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseToken(old, testAud); err != nil {
+		t.Errorf("the token signed by the old key should be valid, got %v", err)
+	}
+	token, _ := GenerateToken(10, "sbChao", false, false, 20, testAud)
+	if strings.Split(token, ".")[1] != "2" {
+		t.Errorf("the token should be signed by the new key, got %s", token)
+	}
+
+	// the old key is removed
+	SetTokenKeys("2:new key")
+	if _, err := ParseToken(old, testAud); err != errTokenError {
+		t.Errorf("the token signed by the removed key should be rejected, got %v", err)
+	}
+
+	for _, conf := range []string{"", "key", "1:a,1:b", "a.b:key"} {
+		if err := SetTokenKeys(conf); err == nil {
+			t.Errorf("the keys %q should be rejected", conf)
 		}
 	}
-	if isTournament {
-		t.Error("it should not be tournament")
+}
+
+func Test_NonceStore(t *testing.T) {
+	fc := timer.NewFakeClock(time.Unix(1000, 0))
+	ns := NewNonceStoreWithClock(fc)
+	if !ns.Use("a", 1010) {
+		t.Fatal("the nonce is not used yet")
+	}
+	if ns.Use("a", 1010) {
+		t.Error("the nonce is already used")
+	}
+	fc.Advance(11 * time.Second)
+	ns.Use("b", 1030)
+	if len(ns.used) != 1 {
+		t.Errorf("the expired nonce should be deleted, got %v", ns.used)
+	}
+}
+
+func Test_ResumeToken(t *testing.T) {
+	SetTokenKeys("1:resume key")
+	token := GenerateResumeToken(10, 20, "abc")
+	uid, tid, sessId, err := ParseResumeToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid != 10 || tid != 20 || sessId != "abc" {
+		t.Errorf("the token should be of user 10, table 20, session abc, got %d, %d, %s", uid, tid, sessId)
+	}
+
+	// another key or a changed payload
+	SetTokenKeys("1:another key")
+	if _, _, _, err := ParseResumeToken(token); err == nil {
+		t.Error("the token signed by another key should be rejected")
+	}
+	SetTokenKeys("1:resume key")
+	forged := GenerateResumeToken(11, 20, "abc")
+	if _, _, _, err := ParseResumeToken(forged[:strings.Index(forged, ".")] + token[strings.Index(token, "."):]); err == nil {
+		t.Error("the changed payload should be rejected")
 	}
 }